@@ -0,0 +1,201 @@
+package authentication
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ticketmaster/authentication/authorization"
+	"github.com/ticketmaster/authentication/common"
+)
+
+// RequestOptions holds the authentication methods a framework adapter has enabled
+type RequestOptions struct {
+	EnableJwtAuthentication   bool
+	EnableBasicAuthentication bool
+}
+
+// RequestCredentials holds the credentials presented with a request
+type RequestCredentials struct {
+	SessionToken  string
+	Authorization string
+}
+
+// AuthenticationError is returned when a request could not be authenticated. If Challenge is set the adapter should
+// respond with 401 and the WWW-Authenticate challenges, otherwise with 403.
+type AuthenticationError struct {
+	Challenge bool
+	Err       error
+}
+
+func (e *AuthenticationError) Error() string {
+	return e.Err.Error()
+}
+
+func forbidden(err error) error {
+	return &AuthenticationError{Challenge: false, Err: err}
+}
+
+func challenge(err error) error {
+	return &AuthenticationError{Challenge: true, Err: err}
+}
+
+// Authenticate resolves the user for a request from the session token, the Authorization header or anonymous access, in that order.
+// If the session or bearer token was refreshed the new token string is returned, otherwise the returned token string is empty.
+func (m Manager) Authenticate(credentials RequestCredentials, options RequestOptions) (*common.User, string, error) {
+	if options.EnableJwtAuthentication && len(credentials.SessionToken) > 0 {
+		return m.authenticateJwt(credentials.SessionToken)
+	}
+
+	if credentials.Authorization != "" {
+		authHeader := strings.SplitN(credentials.Authorization, " ", 2)
+		if len(authHeader) != 2 {
+			return nil, "", forbidden(errors.New("malformed authentication header"))
+		}
+
+		switch authHeader[0] {
+		case "Bearer":
+			if !options.EnableJwtAuthentication {
+				return nil, "", forbidden(errors.New("JWT provided, but is not enabled for authentication"))
+			}
+
+			return m.authenticateJwt(authHeader[1])
+		case "Basic":
+			if !options.EnableBasicAuthentication {
+				return nil, "", forbidden(errors.New("basic credentials provided, but basic authentication is not enabled"))
+			}
+
+			username, password, err := ParseBasicCredentials(authHeader[1])
+			if err != nil {
+				return nil, "", forbidden(err)
+			}
+
+			user, err := m.ValidateCredentials(username, password)
+			if err != nil {
+				if strings.Index(strings.ToLower(err.Error()), "invalid credentials") == -1 {
+					return nil, "", forbidden(err)
+				}
+
+				return nil, "", challenge(errors.New("Not authorized"))
+			}
+
+			return user, "", nil
+		default:
+			return nil, "", forbidden(errors.New("unrecognized authentication header"))
+		}
+	}
+
+	if m.EnableAnonymousAccess {
+		user, err := m.CreateAnonymousUser()
+		if err != nil {
+			return nil, "", forbidden(err)
+		}
+
+		return user, "", nil
+	}
+
+	if m.Authorization == nil || m.Authorization.Default != "allow" {
+		return nil, "", challenge(errors.New("Not authorized"))
+	}
+
+	return nil, "", forbidden(errors.New("unrecognized user"))
+}
+
+func (m Manager) authenticateJwt(tokenString string) (*common.User, string, error) {
+	user, err := m.CreateUserFromTokenString(tokenString)
+	if err != nil {
+		return nil, "", forbidden(err)
+	}
+
+	// Refresh token as needed
+	newTokenString, err := m.RefreshJwt(user)
+	if err != nil {
+		return nil, "", forbidden(err)
+	}
+	if newTokenString == tokenString {
+		newTokenString = ""
+	}
+
+	return user, newTokenString, nil
+}
+
+// LogoutRequest revokes the session and Bearer tokens presented with a request, see Manager.Logout
+func (m Manager) LogoutRequest(credentials RequestCredentials, refreshToken string, all bool) error {
+	var tokens []string
+	if len(credentials.SessionToken) > 0 {
+		tokens = append(tokens, credentials.SessionToken)
+	}
+	if strings.HasPrefix(credentials.Authorization, "Bearer ") {
+		tokens = append(tokens, strings.TrimPrefix(credentials.Authorization, "Bearer "))
+	}
+	if len(tokens) == 0 {
+		return challenge(errors.New("Not authorized"))
+	}
+
+	for _, tokenString := range tokens {
+		user, err := m.CreateUserFromTokenString(tokenString)
+		if err != nil {
+			return challenge(err)
+		}
+
+		err = m.Logout(user, refreshToken, all)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AuthorizeRequest determines if a user is authorized for the specified action, route and method
+func (m Manager) AuthorizeRequest(u *common.User, action string, route string, method string) bool {
+	return m.EvaluateRequest(u, action, route, method).Allow
+}
+
+// EvaluateRequest returns the authorization decision for the specified action, route and method. The route is the
+// percent-decoded request path, URL.Path. Without an authorization configuration every request is denied by default.
+func (m Manager) EvaluateRequest(u *common.User, action string, route string, method string) authorization.Decision {
+	return m.EvaluateRequestWithHeaders(u, action, route, method, nil)
+}
+
+// EvaluateRequestWithHeaders is EvaluateRequest with the request headers made available to expression rules
+func (m Manager) EvaluateRequestWithHeaders(u *common.User, action string, route string, method string, header http.Header) authorization.Decision {
+	if m.Authorization == nil {
+		return authorization.Decision{Allow: false, Default: true}
+	}
+
+	return m.Authorization.Evaluate(u, authorization.RequestActions(action, route, method, header))
+}
+
+// DecisionHeader is the response header adapters expose authorization decisions in when enabled
+const DecisionHeader = "X-Authorization-Decision"
+
+// Challenges returns the WWW-Authenticate header values for the enabled authentication methods
+func Challenges(options RequestOptions, realm string) []string {
+	var challenges []string
+	if options.EnableBasicAuthentication {
+		challenges = append(challenges, `Basic realm="`+realm+`"`)
+	}
+
+	if options.EnableJwtAuthentication {
+		challenges = append(challenges, "Bearer")
+	}
+
+	return challenges
+}
+
+// ParseBasicCredentials decodes the payload of a Basic Authorization header
+func ParseBasicCredentials(data string) (username, password string, err error) {
+	decodedData, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", "", err
+	}
+	strData := strings.SplitN(string(decodedData), ":", 2)
+	if len(strData) != 2 {
+		return "", "", errors.New("malformed basic credentials")
+	}
+	username = strData[0]
+	password = strData[1]
+	return
+}
@@ -1,63 +1,48 @@
 package authorization
 
 import (
-	"fmt"
 	"regexp"
-	"strings"
 
 	"github.com/ticketmaster/authentication/common"
 	"github.com/golang/glog"
-	"github.com/mitchellh/mapstructure"
 )
 
 // ActionRule is an AuthorizationRule that permits or denies access based on the action
 type ActionRule struct {
 	BaseAuthorizationRule `mapstructure:",squash"`
 	Action                []string
+
+	actions []*regexp.Regexp
 }
 
 // NewActionRule returns a new ActionRule based on the configuration provided
 func NewActionRule(config map[interface{}]interface{}) (authorizationRule, error) {
 	r := &ActionRule{}
-	decodeErr := mapstructure.Decode(config, r)
+	decodeErr := decodeRule(config, r)
 	if decodeErr != nil {
 		return nil, decodeErr
 	}
 
-	var err []string
-	if r.Authorize != "allow" && r.Authorize != "deny" {
-		err = append(err, "authorize parameter must be specified")
-	}
-
+	err := r.validate(false)
 	if len(r.Action) == 0 {
-		err = append(err, "route parameter must be specified")
+		err = append(err, "action: must be specified")
 	}
 
-	if len(r.Role) == 0 {
-		err = append(err, "role parameter must be specified")
-	}
-
-	if len(r.Origin) == 0 {
-		err = append(err, "origin parameter must be specified")
-	}
-
-	for idx, action := range r.Action {
-		_, e := regexp.Compile(action)
-		if e != nil {
-			err = append(err, fmt.Sprintf("Action Index %v: %v", idx, e))
-		}
-	}
-
-	_, e := regexp.Compile(r.Origin)
-	if e != nil {
-		err = append(err, e.Error())
-	}
+	var e []string
+	r.actions, e = compilePatterns("action", r.Action)
+	err = append(err, e...)
+	warnEmptyMatches("Action", r.actions)
 
 	if len(err) == 0 {
 		return r, nil
 	}
 
-	return nil, fmt.Errorf("errors occurred creating route rule: %v", strings.Join(err, "\n"))
+	return nil, &RuleError{Type: "action", Errors: err}
+}
+
+// Type returns the rule type name used in configuration
+func (r ActionRule) Type() string {
+	return "action"
 }
 
 // IsMatch returns if this rule is matched
@@ -67,20 +52,13 @@ func (r ActionRule) IsMatch(user *common.User, actions map[string]string) ruleMa
 		return ruleMatch{IsMatch: false}
 	}
 
-	for idx, actionPattern := range r.Action {
-		rg := regexp.MustCompile(actionPattern)
-		if rg.MatchString("") {
-			glog.Warningf("Action rule: %v at index %v matches an empty string. This is not permitted and the action pattern will be skipped.", actionPattern, idx)
-		}
-
+	for _, rg := range r.actions {
 		match := rg.FindString(action)
-		// TODO Remove
-		glog.Infoln(action, actionPattern, match)
 		if match == "" {
 			continue
 		}
 
-		if regexp.MustCompile(r.Origin).MatchString(user.Origin) && user.HasRole(r.Role) {
+		if r.appliesTo(user) {
 			var allow bool
 			if r.Authorize == "allow" {
 				allow = true
@@ -91,3 +69,12 @@ func (r ActionRule) IsMatch(user *common.User, actions map[string]string) ruleMa
 
 	return ruleMatch{IsMatch: false}
 }
+
+// warnEmptyMatches warns about patterns that match an empty string, since only non-empty matches count
+func warnEmptyMatches(name string, patterns []*regexp.Regexp) {
+	for idx, rg := range patterns {
+		if rg.MatchString("") {
+			glog.Warningf("%s pattern: %v at index %v matches an empty string. This is not permitted and the pattern will only match non-empty strings.", name, rg, idx)
+		}
+	}
+}
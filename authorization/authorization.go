@@ -32,12 +32,19 @@ func init() {
 	supportedRules = make(map[string]RuleConstructor)
 	RegisterSupportedAuthorizationRule("action", NewActionRule)
 	RegisterSupportedAuthorizationRule("route", NewRouteRule)
+	RegisterSupportedAuthorizationRule("claim", NewClaimRule)
+	RegisterSupportedAuthorizationRule("expression", NewExpressionRule)
 }
 
 // Authorization struct holds information about authorization
 type Authorization struct {
-	Default string
-	Rules   []authorizationRule
+	Default  string
+	Strategy string
+	Rules    []authorizationRule
+	// Cache holds recent decisions if enabled with the cache size setting, see DecisionCache
+	Cache *DecisionCache
+
+	index *ruleIndex
 }
 
 // NewAuthorization creates a new Authorization from a configuration map
@@ -49,15 +56,30 @@ func NewAuthorization(config map[string]interface{}) (*Authorization, error) {
 		def = "deny"
 	}
 	authorization.Default = def
-	rules := config["rules"].([]interface{})
+	strategy, ok := config["strategy"].(string)
+	if !ok {
+		strategy = DenyOverrides
+	}
+	if _, ok := combiningStrategies[strategy]; !ok {
+		return nil, fmt.Errorf("unknown authorization strategy: %s", strategy)
+	}
+	authorization.Strategy = strategy
+	rules, ok := config["rules"].([]interface{})
+	if !ok && config["rules"] != nil {
+		return nil, fmt.Errorf("authorization rules must be a list")
+	}
 	for idx, rule := range rules {
-		ruleType := rule.(map[interface{}]interface{})["ruleType"].(string)
+		ruleConfig, ok := rule.(map[interface{}]interface{})
+		if !ok {
+			return nil, fmt.Errorf("authorization rule at index %v must be a map", idx)
+		}
+		ruleType, _ := ruleConfig["ruleType"].(string)
 		glog.V(2).Infof("Detected configuration for authorization rule type: %s at index %v", ruleType, idx)
 
 		added := false
 		for ruleName, ruleCtx := range supportedRules {
 			if ruleType == ruleName {
-				builtRule, err := ruleCtx(rule.(map[interface{}]interface{}))
+				builtRule, err := ruleCtx(ruleConfig)
 				if err != nil {
 					return nil, fmt.Errorf("error creating authorization rule at index %v: %v", idx, err)
 				}
@@ -71,29 +93,132 @@ func NewAuthorization(config map[string]interface{}) (*Authorization, error) {
 			glog.Warningf("could not find type for authorization rule. Skipping rule at index %v", idx)
 		}
 	}
+	authorization.index = newRuleIndex(authorization.Rules)
+
+	cacheSize, err := decisionCacheSize(config["cache"])
+	if err != nil {
+		return nil, err
+	}
+	if cacheSize > 0 {
+		if rulesCacheable(authorization.Rules) {
+			authorization.Cache = NewDecisionCache(cacheSize)
+		} else {
+			glog.Warning("authorization decision cache disabled since claim or expression rules depend on more than roles, origin and route")
+		}
+	}
+
 	return authorization, nil
 }
 
+// SetRules replaces the rules, rebuilding the rule index and dropping cached decisions. The cache is disabled if the
+// new rules cannot be cached. Rules must be replaced with SetRules rather than changed in place.
+func (a *Authorization) SetRules(rules []authorizationRule) {
+	a.Rules = rules
+	a.index = newRuleIndex(rules)
+	if a.Cache == nil {
+		return
+	}
+
+	if rulesCacheable(rules) {
+		a.Cache.Invalidate()
+	} else {
+		glog.Warning("authorization decision cache disabled since claim or expression rules depend on more than roles, origin and route")
+		a.Cache = nil
+	}
+}
+
 // IsAuthorized returns true or false if the user is authorized
 func (a Authorization) IsAuthorized(user *common.User, actions map[string]string) bool {
-	var allow bool
-	if a.Default == "allow" {
-		allow = true
+	return a.Evaluate(user, actions).Allow
+}
+
+// Evaluate matches the user and actions against every rule and returns the decision. The matching rules are combined
+// by the configured Strategy, deny-overrides if none is set.
+func (a Authorization) Evaluate(user *common.User, actions map[string]string) Decision {
+	if a.Cache == nil {
+		return a.evaluate(user, actions)
+	}
+
+	key := a.decisionCacheKey(user, actions)
+	if decision, ok := a.Cache.get(key); ok {
+		return decision
 	}
 
-	var match []ruleMatch
-	for idx, rule := range a.Rules {
+	decision := a.evaluate(user, actions)
+	a.Cache.put(key, decision)
+	return decision
+}
+
+func (a Authorization) evaluate(user *common.User, actions map[string]string) Decision {
+	var matches []RuleMatch
+	for _, idx := range a.candidates(actions) {
+		rule := a.Rules[idx]
 		m := rule.IsMatch(user, actions)
-		if m.IsMatch {
-			if !m.PermitAccess {
-				glog.V(5).Infof("authorized rule hit at index %v, it is a deny rule, so immediately denying access", idx)
-				return false
-			}
-			match = append(match, m)
-			glog.V(5).Infof("authorized rule hit at index %v", idx)
-			allow = true
+		if !m.IsMatch {
+			continue
+		}
+
+		glog.V(5).Infof("authorized rule hit at index %v", idx)
+		matches = append(matches, RuleMatch{Index: idx, Type: rule.Type(), PermitAccess: m.PermitAccess, MatchLength: m.MatchLength, Error: m.Error})
+	}
+
+	return a.decide(matches)
+}
+
+// decide combines the matching rules by the configured Strategy
+func (a Authorization) decide(matches []RuleMatch) Decision {
+	decision := Decision{Allow: a.Default == "allow", Default: true, Matches: matches}
+	combine, ok := combiningStrategies[a.Strategy]
+	if !ok {
+		combine = denyOverrides
+	}
+
+	deciding := combine(decision.Matches)
+	if deciding != nil {
+		decision.Allow = deciding.PermitAccess
+		decision.Default = false
+		decision.Deciding = deciding
+	}
+
+	return decision
+}
+
+// candidates returns the indexes of the rules to evaluate for the request
+func (a Authorization) candidates(actions map[string]string) []int {
+	if a.index != nil && a.index.size <= len(a.Rules) {
+		return a.index.candidates(actions, len(a.Rules))
+	}
+
+	all := make([]int, len(a.Rules))
+	for idx := range a.Rules {
+		all[idx] = idx
+	}
+
+	return all
+}
+
+// decisionCacheSize reads the size of the decision cache from the cache configuration
+func decisionCacheSize(config interface{}) (int, error) {
+	var size interface{}
+	switch c := config.(type) {
+	case nil:
+		return 0, nil
+	case map[interface{}]interface{}:
+		size = c["size"]
+	case map[string]interface{}:
+		size = c["size"]
+	default:
+		return 0, fmt.Errorf("invalid authorization cache configuration")
+	}
+
+	switch s := size.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		if s >= 0 {
+			return s, nil
 		}
 	}
 
-	return allow
+	return 0, fmt.Errorf("invalid authorization cache size: %v", size)
 }
@@ -1,22 +1,118 @@
 package authorization
 
-import "github.com/ticketmaster/authentication/common"
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/ticketmaster/authentication/common"
+)
 
 type authorizationRule interface {
 	IsMatch(user *common.User, actions map[string]string) ruleMatch
+	Type() string
 }
 
-// BaseAuthorizationRule is used as an embedded struct in types that implement the authorizationRule interface to provide common fields
+// BaseAuthorizationRule is used as an embedded struct in types that implement the authorizationRule interface to provide common fields.
+// A rule applies to users holding any of the roles whose origin matches any of the origin patterns. Rules must be created
+// by their constructors, which compile the patterns once; changing the pattern fields afterwards has no effect.
 type BaseAuthorizationRule struct {
 	Authorize string
-	Role      string
-	Origin    string
+	Role      []string
+	Origin    []string
+
+	origins []*regexp.Regexp
+}
+
+// RuleError lists the problems found creating a rule, each located by the field it concerns, e.g. "route[0]: ..."
+type RuleError struct {
+	Type   string
+	Errors []string
+}
+
+func (e *RuleError) Error() string {
+	return fmt.Sprintf("errors occurred creating %s rule: %v", e.Type, strings.Join(e.Errors, "\n"))
+}
+
+// decodeRule decodes a rule configuration, accepting a single value wherever a list is expected
+func decodeRule(config map[interface{}]interface{}, rule interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{WeaklyTypedInput: true, Result: rule})
+	if err != nil {
+		return err
+	}
+
+	return decoder.Decode(config)
+}
+
+// validate returns the errors in the common rule fields and compiles the origin patterns. Role and origin are required
+// unless optional is set.
+func (r *BaseAuthorizationRule) validate(optional bool) []string {
+	var err []string
+	if r.Authorize != "allow" && r.Authorize != "deny" {
+		err = append(err, "authorize: must be allow or deny")
+	}
+
+	if len(r.Role) == 0 && !optional {
+		err = append(err, "role: must be specified")
+	}
+
+	if len(r.Origin) == 0 && !optional {
+		err = append(err, "origin: must be specified")
+	}
+
+	var e []string
+	r.origins, e = compilePatterns("origin", r.Origin)
+	return append(err, e...)
+}
+
+func (r BaseAuthorizationRule) baseRule() BaseAuthorizationRule {
+	return r
+}
+
+// appliesTo returns true if the user holds one of the roles and comes from a matching origin. Rules without roles apply to every role.
+func (r BaseAuthorizationRule) appliesTo(user *common.User) bool {
+	hasRole := len(r.Role) == 0
+	for _, role := range r.Role {
+		if user.HasRole(role) {
+			hasRole = true
+			break
+		}
+	}
+	if !hasRole {
+		return false
+	}
+
+	for _, origin := range r.origins {
+		if origin.MatchString(user.Origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compilePatterns compiles regular expressions, returning an error located by the field name for each invalid pattern
+func compilePatterns(name string, patterns []string) ([]*regexp.Regexp, []string) {
+	var compiled []*regexp.Regexp
+	var err []string
+	for idx, pattern := range patterns {
+		rg, e := regexp.Compile(pattern)
+		if e != nil {
+			err = append(err, fmt.Sprintf("%s[%v]: %v", name, idx, e))
+			continue
+		}
+		compiled = append(compiled, rg)
+	}
+
+	return compiled, err
 }
 
 type ruleMatch struct {
 	IsMatch      bool
 	PermitAccess bool
 	MatchLength  int
+	Error        string
 }
 
 // RuleConstructor is the constructor for authorization rules
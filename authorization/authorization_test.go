@@ -121,21 +121,21 @@ func TestNewAuthorization(t *testing.T) {
 		actionRule := a.Rules[0].(*ActionRule)
 		assert.Equal(t, ".", actionRule.Action[0])
 		assert.Equal(t, "allow", actionRule.Authorize)
-		assert.Equal(t, "testOrigin", actionRule.Origin)
-		assert.Equal(t, "testRole", actionRule.Role)
+		assert.Equal(t, []string{"testOrigin"}, actionRule.Origin)
+		assert.Equal(t, []string{"testRole"}, actionRule.Role)
 
 		routeRule := a.Rules[1].(*RouteRule)
 		assert.Equal(t, "/test/route", routeRule.Route[0])
-		assert.Equal(t, "GET", routeRule.Method)
+		assert.Equal(t, []string{"GET"}, routeRule.Method)
 		assert.Equal(t, "allow", routeRule.Authorize)
-		assert.Equal(t, ".*", routeRule.Origin)
-		assert.Equal(t, "testRole3", routeRule.Role)
+		assert.Equal(t, []string{".*"}, routeRule.Origin)
+		assert.Equal(t, []string{"testRole3"}, routeRule.Role)
 
 		actionRule2 := a.Rules[2].(*ActionRule)
 		assert.Equal(t, "App\\.Index", actionRule2.Action[0])
 		assert.Equal(t, "allow", actionRule2.Authorize)
-		assert.Equal(t, ".*", actionRule2.Origin)
-		assert.Equal(t, "Anonymous", actionRule2.Role)
+		assert.Equal(t, []string{".*"}, actionRule2.Origin)
+		assert.Equal(t, []string{"Anonymous"}, actionRule2.Role)
 	}
 
 	_, err = NewAuthorization(getConfigElement(invalidAuthorization))
@@ -170,10 +170,105 @@ func TestIsAuthorized(t *testing.T) {
 	assert.Equal(t, true, authorization.IsAuthorized(user2, nil))
 	assert.Equal(t, true, authorization.IsAuthorized(user2, map[string]string{"route": "/abc", "action": "App.Index", "method": "GET"}))
 
-	newRule := ActionRule{Action: []string{"App\\.Index"}}
-	newRule.Authorize = "deny"
-	newRule.Origin = "test."
-	newRule.Role = "testRole3"
+	newRule, err := NewActionRule(map[interface{}]interface{}{"action": `App\.Index`, "authorize": "deny", "origin": "test.", "role": "testRole3"})
+	if err != nil {
+		t.Fatal(err)
+	}
 	authorization.Rules = append(authorization.Rules, newRule)
 	assert.Equal(t, false, authorization.IsAuthorized(user2, map[string]string{"route": "/abc", "action": "App.Index", "method": "GET"}))
 }
+
+func TestEvaluate(t *testing.T) {
+	authorization, err := NewAuthorization(getConfigElement(validAuthorization))
+	if err != nil {
+		t.Error(err)
+	}
+
+	user := &common.User{Origin: "testOrigin", Username: "test", Roles: []string{"testRole", "testRole3"}}
+	decision := authorization.Evaluate(user, map[string]string{"route": "/test/route", "action": "App.Index", "method": "GET"})
+	assert.True(t, decision.Allow)
+	assert.False(t, decision.Default)
+	assert.Equal(t, []RuleMatch{
+		{Index: 0, Type: "action", PermitAccess: true, MatchLength: 1},
+		{Index: 1, Type: "route", PermitAccess: true, MatchLength: 11},
+	}, decision.Matches)
+	assert.Equal(t, 1, decision.Deciding.Index)
+	assert.Equal(t, "allow by route rule 1 (match length 11); matched rules: 0,1", decision.String())
+
+	decision = authorization.Evaluate(&common.User{Origin: "other", Roles: []string{"testRole"}}, map[string]string{"route": "/abc", "method": "GET"})
+	assert.False(t, decision.Allow)
+	assert.True(t, decision.Default)
+	assert.Nil(t, decision.Deciding)
+	assert.Equal(t, "deny by default", decision.String())
+
+	// A matching deny rule decides even if allow rules match more specifically
+	newRule, err := NewActionRule(map[interface{}]interface{}{"action": "App", "authorize": "deny", "origin": "test.", "role": "testRole"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorization.Rules = append(authorization.Rules, newRule)
+	decision = authorization.Evaluate(user, map[string]string{"route": "/test/route", "action": "App.Index", "method": "GET"})
+	assert.False(t, decision.Allow)
+	assert.Equal(t, 3, len(decision.Matches))
+	assert.Equal(t, 3, decision.Deciding.Index)
+	assert.Equal(t, 3, decision.Deciding.MatchLength)
+}
+
+var strategyAuthorization = []byte(`
+authorization:
+  default: deny
+  rules:
+    - ruleType: route
+      route:
+        - /api
+      authorize: deny
+      role: testRole
+      origin: ".*"
+    - ruleType: route
+      route:
+        - /api/public
+      authorize: allow
+      role: testRole
+      origin: ".*"
+`)
+
+func TestStrategies(t *testing.T) {
+	user := &common.User{Origin: "testOrigin", Username: "test", Roles: []string{"testRole"}}
+	public := map[string]string{"route": "/api/public", "method": "GET"}
+	private := map[string]string{"route": "/api/private", "method": "GET"}
+
+	authorization, err := NewAuthorization(getConfigElement(strategyAuthorization))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, DenyOverrides, authorization.Strategy)
+
+	tests := []struct {
+		strategy string
+		public   bool
+		deciding int
+	}{
+		{DenyOverrides, false, 0},
+		{PermitOverrides, true, 1},
+		{FirstApplicable, false, 0},
+		{MostSpecificWins, true, 1},
+	}
+	for _, test := range tests {
+		authorization.Strategy = test.strategy
+		decision := authorization.Evaluate(user, public)
+		assert.Equal(t, test.public, decision.Allow, test.strategy)
+		assert.Equal(t, test.deciding, decision.Deciding.Index, test.strategy)
+		assert.False(t, authorization.IsAuthorized(user, private), test.strategy)
+	}
+
+	config := getConfigElement(strategyAuthorization)
+	config["strategy"] = "most-specific-wins"
+	authorization, err = NewAuthorization(config)
+	if assert.NoError(t, err) {
+		assert.Equal(t, MostSpecificWins, authorization.Strategy)
+	}
+
+	config["strategy"] = "unknown"
+	_, err = NewAuthorization(config)
+	assert.EqualError(t, err, "unknown authorization strategy: unknown")
+}
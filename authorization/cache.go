@@ -0,0 +1,172 @@
+package authorization
+
+import (
+	"container/list"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ticketmaster/authentication/common"
+)
+
+// CacheStats holds the counters of a decision cache
+type CacheStats struct {
+	Hits     uint64
+	Misses   uint64
+	Entries  int
+	Capacity int
+}
+
+// DecisionCache is a bounded LRU cache of decisions keyed by the user's roles and origin and the request method,
+// route and action. It is only used when every rule depends on nothing else.
+type DecisionCache struct {
+	capacity int
+	hits     uint64
+	misses   uint64
+	state    atomic.Value
+}
+
+type cacheState struct {
+	mutex   sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type cacheEntry struct {
+	key      string
+	decision Decision
+}
+
+// NewDecisionCache creates a decision cache holding up to capacity decisions
+func NewDecisionCache(capacity int) *DecisionCache {
+	c := &DecisionCache{capacity: capacity}
+	c.Invalidate()
+	return c
+}
+
+// Invalidate atomically drops all cached decisions. Decisions being stored concurrently are dropped as well.
+func (c *DecisionCache) Invalidate() {
+	c.state.Store(&cacheState{entries: make(map[string]*list.Element), order: list.New()})
+}
+
+// Stats returns the hit and miss counters and the number of cached decisions
+func (c *DecisionCache) Stats() CacheStats {
+	s := c.state.Load().(*cacheState)
+	s.mutex.Lock()
+	entries := s.order.Len()
+	s.mutex.Unlock()
+
+	return CacheStats{Hits: atomic.LoadUint64(&c.hits), Misses: atomic.LoadUint64(&c.misses), Entries: entries, Capacity: c.capacity}
+}
+
+func (c *DecisionCache) get(key string) (Decision, bool) {
+	s := c.state.Load().(*cacheState)
+	s.mutex.Lock()
+	element, ok := s.entries[key]
+	if ok {
+		s.order.MoveToFront(element)
+	}
+	s.mutex.Unlock()
+
+	if !ok {
+		atomic.AddUint64(&c.misses, 1)
+		return Decision{}, false
+	}
+
+	atomic.AddUint64(&c.hits, 1)
+	return element.Value.(*cacheEntry).decision.clone(), true
+}
+
+func (c *DecisionCache) put(key string, decision Decision) {
+	s := c.state.Load().(*cacheState)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if element, ok := s.entries[key]; ok {
+		element.Value.(*cacheEntry).decision = decision.clone()
+		s.order.MoveToFront(element)
+		return
+	}
+
+	s.entries[key] = s.order.PushFront(&cacheEntry{key: key, decision: decision.clone()})
+	for s.order.Len() > c.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// clone copies the decision so cached decisions cannot be changed by callers
+func (d Decision) clone() Decision {
+	c := Decision{Allow: d.Allow, Default: d.Default}
+	if d.Matches != nil {
+		c.Matches = append([]RuleMatch(nil), d.Matches...)
+	}
+	if d.Deciding != nil {
+		for idx := range c.Matches {
+			if c.Matches[idx] == *d.Deciding {
+				c.Deciding = &c.Matches[idx]
+				break
+			}
+		}
+	}
+
+	return c
+}
+
+// cacheableRule is implemented by rules that only depend on the user's roles and origin and the request method,
+// route and action
+type cacheableRule interface {
+	cacheable() bool
+}
+
+func (r ActionRule) cacheable() bool {
+	return true
+}
+
+func (r RouteRule) cacheable() bool {
+	return true
+}
+
+// rulesCacheable returns true if decisions for the rules can be cached
+func rulesCacheable(rules []authorizationRule) bool {
+	for _, rule := range rules {
+		c, ok := rule.(cacheableRule)
+		if !ok || !c.cacheable() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// decisionCacheKey normalizes the user and request into a cache key. The strategy and default are part of the key so
+// that changing them does not return stale decisions, while SetRules drops the cached decisions.
+func (a Authorization) decisionCacheKey(user *common.User, actions map[string]string) string {
+	var key strings.Builder
+	key.WriteString(a.Strategy)
+	key.WriteByte(0)
+	key.WriteString(a.Default)
+	key.WriteByte(0)
+	if user == nil {
+		key.WriteString("\x01nil")
+	} else {
+		roles := append([]string(nil), user.Roles...)
+		sort.Strings(roles)
+		key.WriteString(user.Origin)
+		for idx, role := range roles {
+			if idx == 0 || role != roles[idx-1] {
+				key.WriteByte(0)
+				key.WriteString(role)
+			}
+		}
+	}
+	key.WriteByte(1)
+	key.WriteString(strings.ToUpper(actions["method"]))
+	key.WriteByte(0)
+	key.WriteString(actions["route"])
+	key.WriteByte(0)
+	key.WriteString(actions["action"])
+
+	return key.String()
+}
@@ -0,0 +1,118 @@
+package authorization
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ticketmaster/authentication/common"
+)
+
+var cachedAuthorization = []byte(`
+authorization:
+  default: deny
+  cache:
+    size: 2
+  rules:
+    - ruleType: route
+      path: /api/*
+      authorize: allow
+      role: reader
+      origin: ".*"
+    - ruleType: route
+      path: /api/admin
+      authorize: deny
+      role: [reader, guest]
+      origin: ".*"
+`)
+
+func TestDecisionCache(t *testing.T) {
+	authorization, err := NewAuthorization(getConfigElement(cachedAuthorization))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.NotNil(t, authorization.Cache) {
+		return
+	}
+
+	reader := &common.User{Origin: "ldap", Username: "a", Roles: []string{"reader", "guest"}}
+	sameRoles := &common.User{Origin: "ldap", Username: "b", Roles: []string{"guest", "reader", "reader"}}
+	api := map[string]string{"route": "/api/users", "method": "GET"}
+	admin := map[string]string{"route": "/api/admin", "method": "get"}
+
+	decision := authorization.Evaluate(reader, api)
+	assert.True(t, decision.Allow)
+	assert.Equal(t, CacheStats{Hits: 0, Misses: 1, Entries: 1, Capacity: 2}, authorization.Cache.Stats())
+
+	// Users with the same roles and origin share decisions, cached decisions are copies
+	cached := authorization.Evaluate(sameRoles, api)
+	assert.Equal(t, decision, cached)
+	cached.Matches[0].Index = 5
+	assert.Equal(t, 0, authorization.Evaluate(reader, api).Deciding.Index)
+	assert.Equal(t, uint64(2), authorization.Cache.Stats().Hits)
+
+	assert.False(t, authorization.Evaluate(reader, admin).Allow)
+	assert.False(t, authorization.Evaluate(reader, map[string]string{"route": "/api/admin", "method": "GET"}).Allow)
+	assert.Equal(t, uint64(3), authorization.Cache.Stats().Hits)
+
+	// The least recently used decision is evicted
+	authorization.Evaluate(&common.User{Origin: "ldap", Roles: []string{"guest"}}, api)
+	assert.Equal(t, 2, authorization.Cache.Stats().Entries)
+	authorization.Evaluate(sameRoles, admin)
+	assert.Equal(t, uint64(4), authorization.Cache.Stats().Hits)
+	authorization.Evaluate(sameRoles, api)
+	assert.Equal(t, uint64(4), authorization.Cache.Stats().Misses)
+
+	// Changing the strategy or invalidating does not return stale decisions
+	authorization.Strategy = PermitOverrides
+	assert.True(t, authorization.Evaluate(reader, admin).Allow)
+	authorization.Cache.Invalidate()
+	assert.Equal(t, 0, authorization.Cache.Stats().Entries)
+	assert.True(t, authorization.Evaluate(reader, admin).Allow)
+	assert.Equal(t, uint64(6), authorization.Cache.Stats().Misses)
+
+	// Replacing a rule drops the decisions of the previous rules
+	rule, err := NewRouteRule(map[interface{}]interface{}{"path": "/api/*", "authorize": "deny", "role": "reader", "origin": ".*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := append(authorization.Rules[:0:0], authorization.Rules...)
+	rules[0] = rule
+	authorization.SetRules(rules)
+	assert.Equal(t, 0, authorization.Cache.Stats().Entries)
+	assert.False(t, authorization.Evaluate(reader, api).Allow)
+
+	// Rules that can not be cached disable the cache
+	claimRule, err := NewClaimRule(map[interface{}]interface{}{"authorize": "allow", "claims": []interface{}{map[interface{}]interface{}{"claim": "username", "value": "b"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorization.SetRules(append(rules, claimRule))
+	assert.Nil(t, authorization.Cache)
+	assert.False(t, authorization.Evaluate(reader, api).Allow)
+}
+
+func TestDecisionCacheDisabled(t *testing.T) {
+	authorization, err := NewAuthorization(getConfigElement([]byte(`
+authorization:
+  cache:
+    size: 100
+  rules:
+    - ruleType: claim
+      authorize: allow
+      claims:
+        - claim: email
+          value: a@example.com
+`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, authorization.Cache)
+
+	_, err = NewAuthorization(getConfigElement([]byte(`
+authorization:
+  cache:
+    size: -1
+  rules: []
+`)))
+	assert.EqualError(t, err, "invalid authorization cache size: -1")
+}
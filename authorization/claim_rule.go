@@ -0,0 +1,259 @@
+package authorization
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/ticketmaster/authentication/common"
+)
+
+// ClaimRule is an AuthorizationRule that permits or denies access based on user attributes and token claims. The rule
+// can be limited to paths, methods and actions; all of its conditions must hold for it to match. Role and origin are
+// optional and default to any.
+type ClaimRule struct {
+	BaseAuthorizationRule `mapstructure:",squash"`
+	Method                []string
+	Path                  []string
+	Action                []string
+	Claims                []*ClaimCondition
+
+	paths   []*pathTemplate
+	actions []*regexp.Regexp
+}
+
+// ClaimCondition tests a user attribute (username, name, email, origin, roles) or a claim of the user's token. Nested
+// claims are addressed with dots, e.g. address.country. Operator is one of equals, in, regex or exists.
+type ClaimCondition struct {
+	Claim    string
+	Operator string
+	Value    string
+	Values   []string
+
+	regex *regexp.Regexp
+}
+
+// NewClaimRule returns a new ClaimRule based on the configuration provided
+func NewClaimRule(config map[interface{}]interface{}) (authorizationRule, error) {
+	r := &ClaimRule{}
+	decodeErr := decodeRule(config, r)
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+
+	if len(r.Origin) == 0 {
+		r.Origin = []string{".*"}
+	}
+
+	err := r.validate(true)
+	if len(r.Claims) == 0 {
+		err = append(err, "claims: must be specified")
+	}
+
+	for idx, c := range r.Claims {
+		e := c.compile()
+		if e != nil {
+			err = append(err, fmt.Sprintf("claims[%v]: %v", idx, e))
+		}
+	}
+
+	for idx, path := range r.Path {
+		template, e := compilePathTemplate(path)
+		if e != nil {
+			err = append(err, fmt.Sprintf("path[%v]: %v", idx, e))
+			continue
+		}
+		r.paths = append(r.paths, template)
+	}
+
+	var e []string
+	r.actions, e = compilePatterns("action", r.Action)
+	err = append(err, e...)
+
+	if len(err) == 0 {
+		return r, nil
+	}
+
+	return nil, &RuleError{Type: "claim", Errors: err}
+}
+
+func (c *ClaimCondition) compile() error {
+	if len(c.Claim) == 0 {
+		return fmt.Errorf("claim must be specified")
+	}
+
+	switch c.Operator {
+	case "", "equals":
+		c.Operator = "equals"
+	case "in":
+		if len(c.Values) == 0 {
+			return fmt.Errorf("values must be specified for operator in")
+		}
+	case "regex":
+		var err error
+		c.regex, err = regexp.Compile(c.Value)
+		if err != nil {
+			return err
+		}
+	case "exists":
+	default:
+		return fmt.Errorf("unknown operator: %s", c.Operator)
+	}
+
+	return nil
+}
+
+// Type returns the rule type name used in configuration
+func (r ClaimRule) Type() string {
+	return "claim"
+}
+
+// IsMatch returns if this rule is matched
+func (r ClaimRule) IsMatch(user *common.User, actions map[string]string) ruleMatch {
+	if user == nil || !r.appliesTo(user) {
+		return ruleMatch{IsMatch: false}
+	}
+
+	matchLength, ok := r.matchesRequest(actions)
+	if !ok {
+		return ruleMatch{IsMatch: false}
+	}
+
+	for _, c := range r.Claims {
+		if !c.isMatch(user) {
+			return ruleMatch{IsMatch: false}
+		}
+	}
+
+	var allow bool
+	if r.Authorize == "allow" {
+		allow = true
+	}
+	return ruleMatch{IsMatch: true, PermitAccess: allow, MatchLength: matchLength}
+}
+
+// matchesRequest checks the optional method, path and action scope of the rule
+func (r ClaimRule) matchesRequest(actions map[string]string) (int, bool) {
+	if len(r.Method) > 0 && !matchesMethod(r.Method, actions["method"]) {
+		return 0, false
+	}
+
+	matchLength := 0
+	if len(r.paths) > 0 {
+		path := requestPath(actions["route"])
+		found := false
+		for _, template := range r.paths {
+			if ok, length := template.Match(path); ok {
+				found = true
+				matchLength = length
+				break
+			}
+		}
+		if !found {
+			return 0, false
+		}
+	}
+
+	if len(r.Action) > 0 {
+		found := false
+		for _, rg := range r.actions {
+			match := rg.FindString(actions["action"])
+			if match != "" {
+				found = true
+				matchLength += len(match)
+				break
+			}
+		}
+		if !found {
+			return 0, false
+		}
+	}
+
+	return matchLength, true
+}
+
+func (c *ClaimCondition) isMatch(user *common.User) bool {
+	values := userClaim(user, c.Claim)
+	switch c.Operator {
+	case "exists":
+		return len(values) > 0
+	case "in":
+		for _, v := range values {
+			for _, allowed := range c.Values {
+				if v == allowed {
+					return true
+				}
+			}
+		}
+	case "regex":
+		for _, v := range values {
+			if c.regex.MatchString(v) {
+				return true
+			}
+		}
+	default:
+		for _, v := range values {
+			if v == c.Value {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// userClaim returns the non-empty values of a user attribute or token claim
+func userClaim(user *common.User, name string) []string {
+	var value interface{}
+	switch name {
+	case "username":
+		value = user.Username
+	case "name":
+		value = user.Name
+	case "email":
+		value = user.Email
+	case "origin":
+		value = user.Origin
+	case "roles":
+		value = user.Roles
+	default:
+		if user.Token == nil {
+			return nil
+		}
+		claims, ok := user.Token.Claims.(jwt.MapClaims)
+		if !ok {
+			return nil
+		}
+		value = map[string]interface{}(claims)
+		for _, key := range strings.Split(name, ".") {
+			m, ok := value.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			value = m[key]
+		}
+	}
+
+	var values []string
+	switch v := value.(type) {
+	case nil:
+	case []string:
+		values = append(values, v...)
+	case []interface{}:
+		for _, item := range v {
+			values = append(values, fmt.Sprint(item))
+		}
+	default:
+		values = append(values, fmt.Sprint(v))
+	}
+
+	var nonEmpty []string
+	for _, v := range values {
+		if len(v) > 0 {
+			nonEmpty = append(nonEmpty, v)
+		}
+	}
+
+	return nonEmpty
+}
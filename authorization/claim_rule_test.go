@@ -0,0 +1,79 @@
+package authorization
+
+import (
+	"testing"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/ticketmaster/authentication/common"
+)
+
+var claimAuthorization = []byte(`
+authorization:
+  default: deny
+  rules:
+    - ruleType: claim
+      method: POST
+      path:
+        - /admin/*
+      authorize: allow
+      claims:
+        - claim: email
+          operator: regex
+          value: "@ticketmaster\\.com$"
+    - ruleType: claim
+      authorize: deny
+      claims:
+        - claim: org.tier
+          operator: in
+          values:
+            - suspended
+            - trial
+    - ruleType: claim
+      authorize: allow
+      role: reader
+      path:
+        - /reports
+      claims:
+        - claim: mfa
+          operator: exists
+        - claim: username
+          value: auditor
+`)
+
+func TestClaimRule(t *testing.T) {
+	authorization, err := NewAuthorization(getConfigElement(claimAuthorization))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	employee := &common.User{Origin: "ldap", Username: "jdoe", Email: "jdoe@ticketmaster.com"}
+	contractor := &common.User{Origin: "ldap", Username: "jroe", Email: "jroe@example.com"}
+	admin := map[string]string{"route": "/admin/users", "method": "POST"}
+	assert.True(t, authorization.IsAuthorized(employee, admin))
+	assert.False(t, authorization.IsAuthorized(contractor, admin))
+	assert.False(t, authorization.IsAuthorized(employee, map[string]string{"route": "/admin/users", "method": "DELETE"}))
+
+	// Nested token claims
+	employee.Token = &jwt.Token{Claims: jwt.MapClaims{"org": map[string]interface{}{"tier": "trial"}}}
+	assert.False(t, authorization.IsAuthorized(employee, admin))
+
+	auditor := &common.User{Origin: "ldap", Username: "auditor", Roles: []string{"reader"}}
+	reports := map[string]string{"route": "/reports", "method": "GET"}
+	assert.False(t, authorization.IsAuthorized(auditor, reports))
+	auditor.Token = &jwt.Token{Claims: jwt.MapClaims{"mfa": []interface{}{"otp"}}}
+	assert.True(t, authorization.IsAuthorized(auditor, reports))
+	auditor.Roles = nil
+	assert.False(t, authorization.IsAuthorized(auditor, reports))
+
+	invalid := []map[interface{}]interface{}{
+		{"authorize": "allow"},
+		{"authorize": "allow", "claims": []interface{}{map[interface{}]interface{}{"claim": "email", "operator": "like"}}},
+		{"authorize": "allow", "claims": []interface{}{map[interface{}]interface{}{"claim": "email", "operator": "regex", "value": "("}}},
+		{"authorize": "allow", "claims": []interface{}{map[interface{}]interface{}{"claim": "email", "operator": "in"}}},
+	}
+	for _, config := range invalid {
+		_, err = NewClaimRule(config)
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,49 @@
+package authorization
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RuleMatch identifies a rule that matched a request. Error is set if a deny rule matched because it failed to
+// evaluate.
+type RuleMatch struct {
+	Index        int
+	Type         string
+	PermitAccess bool
+	MatchLength  int
+	Error        string
+}
+
+// Decision is the outcome of evaluating the authorization rules for a request. If no rule matched, Default is set and
+// Allow reflects the configured default. Otherwise Deciding is the rule that determined the outcome.
+type Decision struct {
+	Allow    bool
+	Default  bool
+	Matches  []RuleMatch
+	Deciding *RuleMatch
+}
+
+// String summarizes the decision for logs and debug headers
+func (d Decision) String() string {
+	outcome := "deny"
+	if d.Allow {
+		outcome = "allow"
+	}
+
+	if d.Deciding == nil {
+		return outcome + " by default"
+	}
+
+	var matches []string
+	for _, m := range d.Matches {
+		matches = append(matches, fmt.Sprintf("%v", m.Index))
+	}
+
+	detail := fmt.Sprintf("match length %v", d.Deciding.MatchLength)
+	if len(d.Deciding.Error) > 0 {
+		detail = "failed closed: " + d.Deciding.Error
+	}
+
+	return fmt.Sprintf("%s by %s rule %v (%s); matched rules: %s", outcome, d.Deciding.Type, d.Deciding.Index, detail, strings.Join(matches, ","))
+}
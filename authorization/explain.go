@@ -0,0 +1,223 @@
+package authorization
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ticketmaster/authentication/common"
+)
+
+// RuleExplanation is the outcome of a single rule for a request. Reason says why the rule did not match, or why a deny
+// rule that failed to evaluate matched.
+type RuleExplanation struct {
+	Index     int
+	Type      string
+	Authorize string
+	Matched   bool
+	Reason    string
+}
+
+// Explanation is the Decision for a request together with the outcome of every rule, see Authorization.Explain
+type Explanation struct {
+	Decision
+	Strategy string
+	Rules    []RuleExplanation
+}
+
+// explainer is implemented by rules that can tell why they do not match a request
+type explainer interface {
+	mismatch(user *common.User, actions map[string]string) string
+}
+
+// Explain evaluates a request like Evaluate and reports for every rule whether it matched and, if not, why. The rule
+// index and the decision cache are bypassed so that every rule is evaluated.
+func (a Authorization) Explain(user *common.User, actions map[string]string) Explanation {
+	explanation := Explanation{Strategy: a.Strategy}
+	if len(explanation.Strategy) == 0 {
+		explanation.Strategy = DenyOverrides
+	}
+
+	var matches []RuleMatch
+	for idx, rule := range a.Rules {
+		e := RuleExplanation{Index: idx, Type: rule.Type()}
+		if base, ok := rule.(interface{ baseRule() BaseAuthorizationRule }); ok {
+			e.Authorize = base.baseRule().Authorize
+		}
+
+		m := rule.IsMatch(user, actions)
+		if m.IsMatch {
+			e.Matched = true
+			e.Reason = m.Error
+			matches = append(matches, RuleMatch{Index: idx, Type: rule.Type(), PermitAccess: m.PermitAccess, MatchLength: m.MatchLength, Error: m.Error})
+		} else if ex, ok := rule.(explainer); ok {
+			e.Reason = ex.mismatch(user, actions)
+		}
+		if !e.Matched && len(e.Reason) == 0 {
+			e.Reason = "did not match"
+		}
+
+		explanation.Rules = append(explanation.Rules, e)
+	}
+	explanation.Decision = a.decide(matches)
+
+	return explanation
+}
+
+// String lists the decision followed by the outcome of every rule
+func (e Explanation) String() string {
+	lines := []string{fmt.Sprintf("%s (%s)", e.Decision, e.Strategy)}
+	for _, r := range e.Rules {
+		outcome := "no match: " + r.Reason
+		if r.Matched {
+			outcome = "match"
+			if e.Deciding != nil && e.Deciding.Index == r.Index {
+				outcome = "match, deciding"
+			}
+			if len(r.Reason) > 0 {
+				outcome += ": failed closed: " + r.Reason
+			}
+		}
+		lines = append(lines, fmt.Sprintf("  rule %v %s %s: %s", r.Index, r.Type, r.Authorize, outcome))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// userMismatch explains why the rule does not apply to the user
+func (r BaseAuthorizationRule) userMismatch(user *common.User) string {
+	if user == nil {
+		return "no user"
+	}
+
+	hasRole := len(r.Role) == 0
+	for _, role := range r.Role {
+		hasRole = hasRole || user.HasRole(role)
+	}
+	if !hasRole {
+		return fmt.Sprintf("user holds none of the roles %s", strings.Join(r.Role, ", "))
+	}
+
+	if !r.appliesTo(user) {
+		return fmt.Sprintf("origin %s does not match %s", user.Origin, strings.Join(r.Origin, ", "))
+	}
+
+	return ""
+}
+
+func (r ActionRule) mismatch(user *common.User, actions map[string]string) string {
+	action := actions["action"]
+	if action == "" {
+		return "request has no action"
+	}
+
+	if !matchesPatterns(r.actions, action) {
+		return fmt.Sprintf("action %s does not match %s", action, strings.Join(r.Action, ", "))
+	}
+
+	return r.userMismatch(user)
+}
+
+func (r RouteRule) mismatch(user *common.User, actions map[string]string) string {
+	route := actions["route"]
+	method := actions["method"]
+	if route == "" || method == "" {
+		return "request has no route or method"
+	}
+
+	if !matchesMethod(r.Method, method) {
+		return fmt.Sprintf("method %s is not one of %s", method, strings.Join(r.Method, ", "))
+	}
+
+	if reason := r.userMismatch(user); len(reason) > 0 {
+		return reason
+	}
+
+	if matchesPaths(r.paths, route) || matchesPatterns(r.routes, requestPath(route)) {
+		return ""
+	}
+
+	patterns := append(append([]string{}, r.Path...), r.regexRoutes()...)
+	return fmt.Sprintf("route %s does not match %s", route, strings.Join(patterns, ", "))
+}
+
+func (r ClaimRule) mismatch(user *common.User, actions map[string]string) string {
+	if reason := r.userMismatch(user); len(reason) > 0 {
+		return reason
+	}
+
+	if len(r.Method) > 0 && !matchesMethod(r.Method, actions["method"]) {
+		return fmt.Sprintf("method %s is not one of %s", actions["method"], strings.Join(r.Method, ", "))
+	}
+
+	if len(r.paths) > 0 && !matchesPaths(r.paths, actions["route"]) {
+		return fmt.Sprintf("route %s does not match %s", actions["route"], strings.Join(r.Path, ", "))
+	}
+
+	if len(r.Action) > 0 && !matchesPatterns(r.actions, actions["action"]) {
+		return fmt.Sprintf("action %s does not match %s", actions["action"], strings.Join(r.Action, ", "))
+	}
+
+	for _, c := range r.Claims {
+		if !c.isMatch(user) {
+			return c.mismatch(user)
+		}
+	}
+
+	return ""
+}
+
+func (c ClaimCondition) mismatch(user *common.User) string {
+	var expected string
+	switch c.Operator {
+	case "exists":
+		return fmt.Sprintf("claim %s is not set", c.Claim)
+	case "in":
+		expected = "one of " + strings.Join(c.Values, ", ")
+	case "regex":
+		expected = "a match of " + c.Value
+	default:
+		expected = c.Value
+	}
+
+	return fmt.Sprintf("claim %s is [%s], expected %s", c.Claim, strings.Join(userClaim(user, c.Claim), ", "), expected)
+}
+
+func (r ExpressionRule) mismatch(user *common.User, actions map[string]string) string {
+	if reason := r.userMismatch(user); len(reason) > 0 {
+		return reason
+	}
+
+	result, err := r.program.Evaluate(requestVariables(user, actions))
+	if err != nil {
+		return fmt.Sprintf("expression failed: %v", err)
+	}
+	if !result {
+		return "expression is false"
+	}
+
+	return ""
+}
+
+// matchesPaths returns true if any path template matches the route
+func matchesPaths(templates []*pathTemplate, route string) bool {
+	path := requestPath(route)
+	for _, template := range templates {
+		if ok, _ := template.Match(path); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesPatterns returns true if any pattern has a non-empty match in the value
+func matchesPatterns(patterns []*regexp.Regexp, value string) bool {
+	for _, rg := range patterns {
+		if rg.FindString(value) != "" {
+			return true
+		}
+	}
+
+	return false
+}
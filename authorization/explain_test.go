@@ -0,0 +1,165 @@
+package authorization
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var explainAuthorization = []byte(`
+authorization:
+  default: deny
+  rules:
+    - ruleType: route
+      path: /api/*
+      method: GET
+      authorize: allow
+      role: reader
+      origin: corp
+    - ruleType: route
+      path: /api/admin/*
+      method: "*"
+      authorize: deny
+      role: [reader, writer]
+      origin: ".*"
+    - ruleType: action
+      action: App\.Index
+      authorize: allow
+      role: reader
+      origin: corp
+    - ruleType: claim
+      authorize: allow
+      path: /api/*
+      claims:
+        - claim: groups
+          operator: in
+          values: [admins]
+    - ruleType: expression
+      authorize: allow
+      expression: "request.path.startsWith('/public/')"
+`)
+
+func TestExplain(t *testing.T) {
+	auth, err := NewAuthorization(getConfigElement(explainAuthorization))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := PolicyCase{User: "jdoe", Origin: "corp", Roles: []string{"reader"}, Route: "/api/users", Claims: map[string]interface{}{"groups": []interface{}{"users"}}}
+	explanation := auth.Explain(c.NewUser(), c.Actions())
+	assert.Equal(t, auth.Evaluate(c.NewUser(), c.Actions()), explanation.Decision)
+	assert.Equal(t, `allow by route rule 0 (match length 4); matched rules: 0 (deny-overrides)
+  rule 0 route allow: match, deciding
+  rule 1 route deny: no match: route /api/users does not match /api/admin/*
+  rule 2 action allow: no match: request has no action
+  rule 3 claim allow: no match: claim groups is [users], expected one of admins
+  rule 4 expression allow: no match: expression is false`, explanation.String())
+
+	c = PolicyCase{User: "jdoe", Origin: "partner", Roles: []string{"writer"}, Method: "DELETE", Route: "/api/admin/users", Action: "App.Index"}
+	explanation = auth.Explain(c.NewUser(), c.Actions())
+	assert.False(t, explanation.Allow)
+	assert.Equal(t, []RuleExplanation{
+		{Index: 0, Type: "route", Authorize: "allow", Reason: "method DELETE is not one of GET"},
+		{Index: 1, Type: "route", Authorize: "deny", Matched: true},
+		{Index: 2, Type: "action", Authorize: "allow", Reason: "user holds none of the roles reader"},
+		{Index: 3, Type: "claim", Authorize: "allow", Reason: "claim groups is [], expected one of admins"},
+		{Index: 4, Type: "expression", Authorize: "allow", Reason: "expression is false"},
+	}, explanation.Rules)
+
+	c = PolicyCase{Origin: "partner", Roles: []string{"reader"}, Action: "App.Index"}
+	explanation = auth.Explain(c.NewUser(), c.Actions())
+	assert.Equal(t, "origin partner does not match corp", explanation.Rules[2].Reason)
+	assert.True(t, explanation.Default)
+}
+
+func TestLoadPolicyCases(t *testing.T) {
+	dir, err := ioutil.TempDir("", "policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "cases.yaml")
+	err = ioutil.WriteFile(path, []byte(`
+- name: admins read users
+  user: jdoe
+  fullName: John Doe
+  email: jdoe@example.com
+  origin: corp
+  claims:
+    groups: [admins]
+    tenant:
+      id: 7
+  route: /api/users
+  expect: allow
+- user: jdoe
+  action: App.Index
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases, err := LoadPolicyCases(path)
+	if assert.Nil(t, err) && assert.Equal(t, 2, len(cases)) {
+		assert.Equal(t, "admins read users", cases[0].String())
+		assert.Equal(t, "GET", cases[0].Actions()["method"])
+		assert.Equal(t, []string{"7"}, userClaim(cases[0].NewUser(), "tenant.id"))
+		assert.Equal(t, []string{"John Doe"}, userClaim(cases[0].NewUser(), "name"))
+		assert.Equal(t, []string{"jdoe@example.com"}, userClaim(cases[0].NewUser(), "email"))
+		assert.Equal(t, "/jdoe [] App.Index", cases[1].String())
+	}
+
+	err = ioutil.WriteFile(path, []byte("- user: jdoe\n  expect: permit\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadPolicyCases(path)
+	assert.EqualError(t, err, "error reading "+path+": case 0: expect must be allow or deny")
+
+	err = ioutil.WriteFile(path, []byte("- user: jdoe\n  role: admin\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadPolicyCases(path)
+	assert.Error(t, err)
+}
+
+func TestRunPolicyTests(t *testing.T) {
+	auth, err := NewAuthorization(getConfigElement(explainAuthorization))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	RunPolicyTests(t, auth, "testdata/policy_test.yaml")
+
+	// An accidental grant fails the case that expects a deny
+	explanation, err := auth.CheckPolicyCase(PolicyCase{Origin: "corp", Roles: []string{"reader"}, Method: "PUT", Route: "/api/users/1", Expect: "allow"})
+	assert.EqualError(t, err, "expected allow")
+	assert.True(t, explanation.Default)
+
+	_, err = auth.CheckPolicyCase(PolicyCase{Origin: "corp", Roles: []string{"reader"}, Route: "/api/users/1"})
+	assert.Nil(t, err)
+
+	// Cases set the email of the user for claim rules on email domains
+	emailAuth, err := NewAuthorization(getConfigElement([]byte(`
+authorization:
+  default: deny
+  rules:
+    - ruleType: claim
+      authorize: allow
+      claims:
+        - claim: email
+          operator: regex
+          value: "@example\\.com$"
+`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = emailAuth.CheckPolicyCase(PolicyCase{Email: "jdoe@example.com", Route: "/api/users", Expect: "allow"})
+	assert.Nil(t, err)
+	_, err = emailAuth.CheckPolicyCase(PolicyCase{Email: "jdoe@example.org", Route: "/api/users", Expect: "allow"})
+	assert.EqualError(t, err, "expected allow")
+}
@@ -0,0 +1,784 @@
+package authorization
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+/*
+Expressions are a small, side effect free language evaluated over the variables user, request and action:
+
+	user.email.endsWith("@ticketmaster.com") && request.method in ["POST", "PUT"]
+	"admin" in user.roles || (has(user.claims.scope) && user.claims.scope.contains("admin"))
+	request.headers["x-tenant"] == user.claims.tenant && size(user.roles) > 0
+
+Operators by increasing precedence: ||, &&, comparisons (== != < <= > >= in), unary !, then field access, indexing and
+method calls. Functions: has, size, lower, upper. Methods: startsWith, endsWith, contains, matches.
+*/
+
+// ExpressionError reports an error in an expression with the 1-based position it occurred at
+type ExpressionError struct {
+	Position int
+	Message  string
+}
+
+func (e *ExpressionError) Error() string {
+	return fmt.Sprintf("%s at position %v", e.Message, e.Position)
+}
+
+var expressionVariables = map[string]bool{"user": true, "request": true, "action": true}
+
+var expressionFunctions = map[string]int{"has": 1, "size": 1, "lower": 1, "upper": 1}
+
+var expressionMethods = map[string]int{"startsWith": 1, "endsWith": 1, "contains": 1, "matches": 1}
+
+// Expression is a compiled policy expression
+type Expression struct {
+	Source string
+	root   expressionNode
+}
+
+// CompileExpression parses an expression, returning an *ExpressionError if it is invalid
+func CompileExpression(source string) (*Expression, error) {
+	tokens, err := tokenizeExpression(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &expressionParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, p.errorf("unexpected %s", p.peek())
+	}
+
+	return &Expression{Source: source, root: root}, nil
+}
+
+// Evaluate evaluates the expression over the variables. Expressions that do not evaluate to a boolean return an error.
+func (e *Expression) Evaluate(variables map[string]interface{}) (bool, error) {
+	value, err := e.root.eval(variables)
+	if err != nil {
+		return false, err
+	}
+
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression evaluated to %s, not a boolean", typeName(value))
+	}
+
+	return result, nil
+}
+
+// Tokens
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+type expressionToken struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+func (t expressionToken) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return strconv.Quote(t.value.(string))
+	}
+
+	return "'" + t.text + "'"
+}
+
+var twoCharOperators = []string{"==", "!=", "<=", ">=", "&&", "||"}
+
+func tokenizeExpression(source string) ([]expressionToken, error) {
+	var tokens []expressionToken
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, expressionToken{kind: tokenIdent, text: string(runes[start:i]), pos: pos})
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			text := string(runes[start:i])
+			value, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, &ExpressionError{pos, "invalid number " + text}
+			}
+			tokens = append(tokens, expressionToken{kind: tokenNumber, text: text, value: value, pos: pos})
+		case r == '"' || r == '\'':
+			var sb strings.Builder
+			i++
+			closed := false
+			for i < len(runes) {
+				c := runes[i]
+				if c == r {
+					closed = true
+					i++
+					break
+				}
+				if c == '\\' && i+1 < len(runes) {
+					i++
+					switch runes[i] {
+					case 'n':
+						c = '\n'
+					case 't':
+						c = '\t'
+					default:
+						c = runes[i]
+					}
+				}
+				sb.WriteRune(c)
+				i++
+			}
+			if !closed {
+				return nil, &ExpressionError{pos, "unterminated string"}
+			}
+			tokens = append(tokens, expressionToken{kind: tokenString, text: string(runes[pos-1 : i]), value: sb.String(), pos: pos})
+		default:
+			matched := false
+			if i+1 < len(runes) {
+				two := string(runes[i : i+2])
+				for _, op := range twoCharOperators {
+					if two == op {
+						tokens = append(tokens, expressionToken{kind: tokenOperator, text: op, pos: pos})
+						i += 2
+						matched = true
+						break
+					}
+				}
+			}
+			if matched {
+				continue
+			}
+			if strings.ContainsRune("()[],.!<>", r) {
+				tokens = append(tokens, expressionToken{kind: tokenOperator, text: string(r), pos: pos})
+				i++
+				continue
+			}
+			return nil, &ExpressionError{pos, fmt.Sprintf("unexpected character '%c'", r)}
+		}
+	}
+
+	return append(tokens, expressionToken{kind: tokenEOF, pos: len(runes) + 1}), nil
+}
+
+// Parser
+
+type expressionParser struct {
+	tokens []expressionToken
+	pos    int
+}
+
+func (p *expressionParser) peek() expressionToken {
+	return p.tokens[p.pos]
+}
+
+func (p *expressionParser) next() expressionToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *expressionParser) accept(text string) bool {
+	t := p.peek()
+	if (t.kind == tokenOperator || t.kind == tokenIdent) && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *expressionParser) expect(text string) error {
+	if !p.accept(text) {
+		return p.errorf("expected '%s' but found %s", text, p.peek())
+	}
+	return nil
+}
+
+func (p *expressionParser) errorf(format string, args ...interface{}) error {
+	return &ExpressionError{p.peek().pos, fmt.Sprintf(format, args...)}
+}
+
+func (p *expressionParser) parseOr() (expressionNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t := p.peek()
+		if !p.accept("||") {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "||", left: left, right: right, pos: t.pos}
+	}
+}
+
+func (p *expressionParser) parseAnd() (expressionNode, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t := p.peek()
+		if !p.accept("&&") {
+			return left, nil
+		}
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "&&", left: left, right: right, pos: t.pos}
+	}
+}
+
+func (p *expressionParser) parseComparison() (expressionNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">", "in"} {
+		if p.accept(op) {
+			right, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			return &comparisonNode{op: op, left: left, right: right, pos: t.pos}, nil
+		}
+	}
+
+	return left, nil
+}
+
+func (p *expressionParser) parseUnary() (expressionNode, error) {
+	t := p.peek()
+	if p.accept("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand, pos: t.pos}, nil
+	}
+
+	return p.parsePostfix()
+}
+
+func (p *expressionParser) parsePostfix() (expressionNode, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t := p.peek()
+		switch {
+		case p.accept("."):
+			name := p.next()
+			if name.kind != tokenIdent {
+				return nil, &ExpressionError{name.pos, fmt.Sprintf("expected a field or method name but found %s", name)}
+			}
+			if p.peek().text == "(" && p.peek().kind == tokenOperator {
+				node, err = p.parseCall(node, name)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			node = &fieldNode{target: node, name: name.text, pos: name.pos}
+		case p.accept("["):
+			index, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			err = p.expect("]")
+			if err != nil {
+				return nil, err
+			}
+			node = &indexNode{target: node, index: index, pos: t.pos}
+		default:
+			return node, nil
+		}
+	}
+}
+
+func (p *expressionParser) parseCall(receiver expressionNode, name expressionToken) (expressionNode, error) {
+	arity, ok := expressionFunctions[name.text]
+	if receiver != nil {
+		arity, ok = expressionMethods[name.text]
+	}
+	if !ok {
+		return nil, &ExpressionError{name.pos, fmt.Sprintf("unknown function %s", name.text)}
+	}
+
+	err := p.expect("(")
+	if err != nil {
+		return nil, err
+	}
+
+	var args []expressionNode
+	if !p.accept(")") {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.accept(")") {
+				break
+			}
+			err = p.expect(",")
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(args) != arity {
+		return nil, &ExpressionError{name.pos, fmt.Sprintf("%s expects %v argument(s) but got %v", name.text, arity, len(args))}
+	}
+
+	call := &callNode{receiver: receiver, name: name.text, args: args, pos: name.pos}
+	if name.text == "matches" {
+		pattern, ok := args[0].(*literalNode)
+		if !ok {
+			return nil, &ExpressionError{name.pos, "matches expects a string literal pattern"}
+		}
+		s, ok := pattern.value.(string)
+		if !ok {
+			return nil, &ExpressionError{name.pos, "matches expects a string literal pattern"}
+		}
+		call.regex, err = regexp.Compile(s)
+		if err != nil {
+			return nil, &ExpressionError{name.pos, err.Error()}
+		}
+	}
+
+	return call, nil
+}
+
+func (p *expressionParser) parsePrimary() (expressionNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString, tokenNumber:
+		return &literalNode{value: t.value}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		}
+		if p.peek().text == "(" && p.peek().kind == tokenOperator {
+			return p.parseCall(nil, t)
+		}
+		if !expressionVariables[t.text] {
+			return nil, &ExpressionError{t.pos, fmt.Sprintf("unknown variable %s", t.text)}
+		}
+		return &variableNode{name: t.text}, nil
+	case tokenOperator:
+		switch t.text {
+		case "(":
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return node, p.expect(")")
+		case "[":
+			list := &listNode{}
+			if p.accept("]") {
+				return list, nil
+			}
+			for {
+				item, err := p.parseOr()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+				if p.accept("]") {
+					return list, nil
+				}
+				err = p.expect(",")
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return nil, &ExpressionError{t.pos, fmt.Sprintf("unexpected %s", t)}
+}
+
+// Evaluation
+
+type expressionNode interface {
+	eval(variables map[string]interface{}) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(variables map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+type variableNode struct {
+	name string
+}
+
+func (n *variableNode) eval(variables map[string]interface{}) (interface{}, error) {
+	return variables[n.name], nil
+}
+
+type fieldNode struct {
+	target expressionNode
+	name   string
+	pos    int
+}
+
+func (n *fieldNode) eval(variables map[string]interface{}) (interface{}, error) {
+	target, err := n.target.eval(variables)
+	if err != nil || target == nil {
+		return nil, err
+	}
+
+	m, ok := target.(map[string]interface{})
+	if !ok {
+		return nil, &ExpressionError{n.pos, fmt.Sprintf("field %s of %s", n.name, typeName(target))}
+	}
+
+	return m[n.name], nil
+}
+
+type indexNode struct {
+	target expressionNode
+	index  expressionNode
+	pos    int
+}
+
+func (n *indexNode) eval(variables map[string]interface{}) (interface{}, error) {
+	target, err := n.target.eval(variables)
+	if err != nil || target == nil {
+		return nil, err
+	}
+	index, err := n.index.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := target.(type) {
+	case map[string]interface{}:
+		key, ok := index.(string)
+		if ok {
+			return t[key], nil
+		}
+	case []interface{}:
+		i, ok := index.(float64)
+		if ok {
+			if int(i) < 0 || int(i) >= len(t) {
+				return nil, nil
+			}
+			return t[int(i)], nil
+		}
+	}
+
+	return nil, &ExpressionError{n.pos, fmt.Sprintf("cannot index %s with %s", typeName(target), typeName(index))}
+}
+
+type listNode struct {
+	items []expressionNode
+}
+
+func (n *listNode) eval(variables map[string]interface{}) (interface{}, error) {
+	list := make([]interface{}, 0, len(n.items))
+	for _, item := range n.items {
+		value, err := item.eval(variables)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+	}
+
+	return list, nil
+}
+
+type notNode struct {
+	operand expressionNode
+	pos     int
+}
+
+func (n *notNode) eval(variables map[string]interface{}) (interface{}, error) {
+	value, err := n.operand.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+
+	b, ok := value.(bool)
+	if !ok {
+		return nil, &ExpressionError{n.pos, fmt.Sprintf("! of %s", typeName(value))}
+	}
+
+	return !b, nil
+}
+
+type logicalNode struct {
+	op          string
+	left, right expressionNode
+	pos         int
+}
+
+func (n *logicalNode) eval(variables map[string]interface{}) (interface{}, error) {
+	left, err := n.operandBool(n.left, variables)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "&&" && !left {
+		return false, nil
+	}
+	if n.op == "||" && left {
+		return true, nil
+	}
+
+	return n.operandBool(n.right, variables)
+}
+
+func (n *logicalNode) operandBool(operand expressionNode, variables map[string]interface{}) (bool, error) {
+	value, err := operand.eval(variables)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := value.(bool)
+	if !ok {
+		return false, &ExpressionError{n.pos, fmt.Sprintf("%s of %s", n.op, typeName(value))}
+	}
+
+	return b, nil
+}
+
+type comparisonNode struct {
+	op          string
+	left, right expressionNode
+	pos         int
+}
+
+func (n *comparisonNode) eval(variables map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return valuesEqual(left, right), nil
+	case "!=":
+		return !valuesEqual(left, right), nil
+	case "in":
+		switch r := right.(type) {
+		case []interface{}:
+			for _, item := range r {
+				if valuesEqual(left, item) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case map[string]interface{}:
+			key, ok := left.(string)
+			if ok {
+				_, found := r[key]
+				return found, nil
+			}
+		case nil:
+			return false, nil
+		}
+		return nil, &ExpressionError{n.pos, fmt.Sprintf("%s in %s", typeName(left), typeName(right))}
+	}
+
+	var cmp int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return nil, &ExpressionError{n.pos, fmt.Sprintf("cannot compare %s with %s", typeName(left), typeName(right))}
+		}
+		if l < r {
+			cmp = -1
+		} else if l > r {
+			cmp = 1
+		}
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return nil, &ExpressionError{n.pos, fmt.Sprintf("cannot compare %s with %s", typeName(left), typeName(right))}
+		}
+		cmp = strings.Compare(l, r)
+	default:
+		return nil, &ExpressionError{n.pos, fmt.Sprintf("cannot compare %s with %s", typeName(left), typeName(right))}
+	}
+
+	switch n.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	}
+	return cmp >= 0, nil
+}
+
+type callNode struct {
+	receiver expressionNode
+	name     string
+	args     []expressionNode
+	regex    *regexp.Regexp
+	pos      int
+}
+
+func (n *callNode) eval(variables map[string]interface{}) (interface{}, error) {
+	var receiver interface{}
+	var err error
+	if n.receiver != nil {
+		receiver, err = n.receiver.eval(variables)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var args []interface{}
+	for _, arg := range n.args {
+		value, err := arg.eval(variables)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+	}
+
+	switch n.name {
+	case "has":
+		return args[0] != nil, nil
+	case "size":
+		switch v := args[0].(type) {
+		case string:
+			return float64(len(v)), nil
+		case []interface{}:
+			return float64(len(v)), nil
+		case map[string]interface{}:
+			return float64(len(v)), nil
+		case nil:
+			return float64(0), nil
+		}
+	case "lower", "upper":
+		if s, ok := args[0].(string); ok {
+			if n.name == "lower" {
+				return strings.ToLower(s), nil
+			}
+			return strings.ToUpper(s), nil
+		}
+	case "contains":
+		if list, ok := receiver.([]interface{}); ok {
+			for _, item := range list {
+				if valuesEqual(item, args[0]) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+		fallthrough
+	case "startsWith", "endsWith", "matches":
+		s, ok := receiver.(string)
+		if receiver == nil {
+			return false, nil
+		}
+		if !ok {
+			break
+		}
+		if n.regex != nil {
+			return n.regex.MatchString(s), nil
+		}
+		arg, ok := args[0].(string)
+		if !ok {
+			break
+		}
+		switch n.name {
+		case "startsWith":
+			return strings.HasPrefix(s, arg), nil
+		case "endsWith":
+			return strings.HasSuffix(s, arg), nil
+		}
+		return strings.Contains(s, arg), nil
+	}
+
+	target := receiver
+	if n.receiver == nil {
+		target = args[0]
+	}
+	return nil, &ExpressionError{n.pos, fmt.Sprintf("%s of %s", n.name, typeName(target))}
+}
+
+func valuesEqual(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "bool"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	}
+
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,165 @@
+package authorization
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/golang/glog"
+	"github.com/ticketmaster/authentication/common"
+)
+
+// HeaderActionPrefix prefixes request headers passed to rules in the actions map, e.g. "header:x-tenant"
+const HeaderActionPrefix = "header:"
+
+// RequestActions builds the actions map for a request. Header names are lower cased and only their first value is kept.
+func RequestActions(action string, route string, method string, header http.Header) map[string]string {
+	actions := map[string]string{"action": action, "route": route, "method": method}
+	for name, values := range header {
+		if len(values) > 0 {
+			actions[HeaderActionPrefix+strings.ToLower(name)] = values[0]
+		}
+	}
+
+	return actions
+}
+
+// ExpressionRule is an AuthorizationRule that matches when its expression evaluates to true for the user and request,
+// see CompileExpression for the syntax. Role and origin are optional and default to any.
+type ExpressionRule struct {
+	BaseAuthorizationRule `mapstructure:",squash"`
+	Expression            string
+
+	program *Expression
+}
+
+// NewExpressionRule returns a new ExpressionRule based on the configuration provided
+func NewExpressionRule(config map[interface{}]interface{}) (authorizationRule, error) {
+	r := &ExpressionRule{}
+	decodeErr := decodeRule(config, r)
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+
+	if len(r.Origin) == 0 {
+		r.Origin = []string{".*"}
+	}
+
+	err := r.validate(true)
+	if len(r.Expression) == 0 {
+		err = append(err, "expression: must be specified")
+	} else {
+		var e error
+		r.program, e = CompileExpression(r.Expression)
+		if e != nil {
+			err = append(err, fmt.Sprintf("expression: %v", e))
+		}
+	}
+
+	if len(err) == 0 {
+		return r, nil
+	}
+
+	return nil, &RuleError{Type: "expression", Errors: err}
+}
+
+// Type returns the rule type name used in configuration
+func (r ExpressionRule) Type() string {
+	return "expression"
+}
+
+// IsMatch returns if this rule is matched. An expression that fails to evaluate fails closed: a deny rule matches and
+// reports the error, an allow rule does not match.
+func (r ExpressionRule) IsMatch(user *common.User, actions map[string]string) ruleMatch {
+	if user == nil || !r.appliesTo(user) {
+		return ruleMatch{IsMatch: false}
+	}
+
+	result, err := r.program.Evaluate(requestVariables(user, actions))
+	if err != nil {
+		glog.Warningf("error evaluating authorization expression %q: %v", r.Expression, err)
+		if r.Authorize == "deny" {
+			return ruleMatch{IsMatch: true, PermitAccess: false, Error: err.Error()}
+		}
+		return ruleMatch{IsMatch: false}
+	}
+	if !result {
+		return ruleMatch{IsMatch: false}
+	}
+
+	var allow bool
+	if r.Authorize == "allow" {
+		allow = true
+	}
+	return ruleMatch{IsMatch: true, PermitAccess: allow}
+}
+
+// requestVariables exposes the user and request to expressions
+func requestVariables(user *common.User, actions map[string]string) map[string]interface{} {
+	roles := make([]interface{}, 0, len(user.Roles))
+	for _, role := range user.Roles {
+		roles = append(roles, role)
+	}
+
+	claims := map[string]interface{}{}
+	if user.Token != nil {
+		if c, ok := user.Token.Claims.(jwt.MapClaims); ok {
+			claims = normalizeClaims(map[string]interface{}(c)).(map[string]interface{})
+		}
+	}
+
+	headers := map[string]interface{}{}
+	for key, value := range actions {
+		if strings.HasPrefix(key, HeaderActionPrefix) {
+			headers[strings.TrimPrefix(key, HeaderActionPrefix)] = value
+		}
+	}
+
+	return map[string]interface{}{
+		"user": map[string]interface{}{
+			"username": user.Username,
+			"name":     user.Name,
+			"email":    user.Email,
+			"origin":   user.Origin,
+			"roles":    roles,
+			"claims":   claims,
+		},
+		"request": map[string]interface{}{
+			"method":  actions["method"],
+			"path":    requestPath(actions["route"]),
+			"headers": headers,
+		},
+		"action": actions["action"],
+	}
+}
+
+// normalizeClaims converts claim values to the types expressions operate on
+func normalizeClaims(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = normalizeClaims(item)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			list = append(list, normalizeClaims(item))
+		}
+		return list
+	case []string:
+		list := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			list = append(list, item)
+		}
+		return list
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	}
+
+	return value
+}
@@ -0,0 +1,130 @@
+package authorization
+
+import (
+	"net/http"
+	"testing"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/ticketmaster/authentication/common"
+)
+
+var expressionAuthorization = []byte(`
+authorization:
+  default: deny
+  rules:
+    - ruleType: expression
+      authorize: allow
+      expression: >-
+        user.email.endsWith("@ticketmaster.com") && request.method in ["GET", "POST"]
+        && request.path.startsWith("/tenants/") && request.headers["x-tenant"] == user.claims.tenant
+    - ruleType: expression
+      authorize: deny
+      expression: '"suspended" in user.roles || !has(user.claims.tenant)'
+`)
+
+func TestExpressionRule(t *testing.T) {
+	authorization, err := NewAuthorization(getConfigElement(expressionAuthorization))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := &common.User{Origin: "ldap", Username: "jdoe", Email: "jdoe@ticketmaster.com",
+		Token: &jwt.Token{Claims: jwt.MapClaims{"tenant": "acme"}}}
+	header := http.Header{"X-Tenant": []string{"acme"}}
+	assert.True(t, authorization.IsAuthorized(user, RequestActions("", "/tenants/acme?q=1", "GET", header)))
+	assert.False(t, authorization.IsAuthorized(user, RequestActions("", "/tenants/acme", "DELETE", header)))
+	assert.False(t, authorization.IsAuthorized(user, RequestActions("", "/tenants/acme", "GET", http.Header{"X-Tenant": []string{"other"}})))
+	assert.False(t, authorization.IsAuthorized(user, RequestActions("", "/tenants/acme", "GET", nil)))
+
+	user.Roles = []string{"suspended"}
+	assert.False(t, authorization.IsAuthorized(user, RequestActions("", "/tenants/acme", "GET", header)))
+
+	assert.False(t, authorization.IsAuthorized(&common.User{Email: "jdoe@ticketmaster.com"}, RequestActions("", "/tenants/acme", "GET", header)))
+}
+
+func TestExpressionErrors(t *testing.T) {
+	_, err := CompileExpression(`user.email == "a" &&`)
+	assert.EqualError(t, err, "unexpected end of expression at position 21")
+
+	_, err = CompileExpression(`user.email = "a"`)
+	assert.EqualError(t, err, "unexpected character '=' at position 12")
+
+	_, err = CompileExpression(`session.id == "a"`)
+	assert.EqualError(t, err, "unknown variable session at position 1")
+
+	_, err = CompileExpression(`user.name.matches("[")`)
+	assert.Error(t, err)
+	assert.Equal(t, 11, err.(*ExpressionError).Position)
+
+	_, err = CompileExpression(`exec("rm")`)
+	assert.EqualError(t, err, "unknown function exec at position 1")
+
+	_, err = NewAuthorization(getConfigElement([]byte(`
+authorization:
+  rules:
+    - ruleType: expression
+      authorize: allow
+      expression: (action == "App.Index"
+`)))
+	assert.EqualError(t, err, "error creating authorization rule at index 0: errors occurred creating expression rule: expression: expected ')' but found end of expression at position 23")
+
+	// Evaluation errors are returned
+	e, err := CompileExpression(`user.roles > 1`)
+	assert.Nil(t, err)
+	_, err = e.Evaluate(map[string]interface{}{"user": map[string]interface{}{"roles": []interface{}{}}})
+	assert.EqualError(t, err, "cannot compare list with number at position 12")
+}
+
+func TestExpressionFailsClosed(t *testing.T) {
+	auth, err := NewAuthorization(getConfigElement([]byte(`
+authorization:
+  default: allow
+  rules:
+    - ruleType: expression
+      authorize: allow
+      expression: user.claims.level > 2
+    - ruleType: expression
+      authorize: deny
+      expression: user.claims.level < 1
+`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A level that is not a number fails both expressions: the deny rule matches and the allow rule does not
+	user := &common.User{Token: &jwt.Token{Claims: jwt.MapClaims{"level": "high"}}}
+	decision := auth.Evaluate(user, RequestActions("", "/", "GET", nil))
+	assert.False(t, decision.Allow)
+	assert.Equal(t, []RuleMatch{{Index: 1, Type: "expression", Error: "cannot compare string with number at position 19"}}, decision.Matches)
+	assert.Equal(t, "deny by expression rule 1 (failed closed: cannot compare string with number at position 19); matched rules: 1", decision.String())
+
+	explanation := auth.Explain(user, RequestActions("", "/", "GET", nil))
+	assert.Equal(t, `deny by expression rule 1 (failed closed: cannot compare string with number at position 19); matched rules: 1 (deny-overrides)
+  rule 0 expression allow: no match: expression failed: cannot compare string with number at position 19
+  rule 1 expression deny: match, deciding: failed closed: cannot compare string with number at position 19`, explanation.String())
+}
+
+func TestExpressionEvaluate(t *testing.T) {
+	variables := map[string]interface{}{
+		"user":   map[string]interface{}{"roles": []interface{}{"a", "b"}, "claims": map[string]interface{}{"level": float64(3)}},
+		"action": "App.Index",
+	}
+	for source, expected := range map[string]bool{
+		`size(user.roles) == 2 && user.roles[0] == "a"`:   true,
+		`user.claims.level >= 3 && user.claims.level < 4`: true,
+		`"level" in user.claims && !("c" in user.roles)`:  true,
+		`lower(action) == "app.index"`:                    true,
+		`action.matches("^App\\.")`:                       true,
+		`user.roles.contains("c") || false`:               false,
+		`has(user.claims.missing.nested)`:                 false,
+		`user.claims.level != 3`:                          false,
+	} {
+		e, err := CompileExpression(source)
+		if assert.Nil(t, err, source) {
+			result, err := e.Evaluate(variables)
+			assert.Nil(t, err, source)
+			assert.Equal(t, expected, result, source)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package authorization
+
+import (
+	"regexp/syntax"
+	"sort"
+	"strings"
+)
+
+// ruleScope limits the requests a rule can match. Rules implementing scopedRule are only evaluated for requests in
+// their scope, other rules are evaluated for every request.
+type ruleScope struct {
+	// Methods holds the methods the rule can match, nil for any method
+	Methods []string
+	// Segments holds the first path segments the rule can match, nil for any path
+	Segments []string
+}
+
+type scopedRule interface {
+	scope() ruleScope
+}
+
+type indexKey struct {
+	method     string
+	segment    string
+	anyMethod  bool
+	anySegment bool
+}
+
+// ruleIndex buckets rules by method and first path segment so only the rules that can match a request are evaluated
+type ruleIndex struct {
+	size    int
+	buckets map[indexKey][]int
+}
+
+func newRuleIndex(rules []authorizationRule) *ruleIndex {
+	index := &ruleIndex{size: len(rules), buckets: make(map[indexKey][]int)}
+	for idx, rule := range rules {
+		var s ruleScope
+		if scoped, ok := rule.(scopedRule); ok {
+			s = scoped.scope()
+		}
+
+		methods := []indexKey{{anyMethod: true}}
+		if s.Methods != nil {
+			methods = methods[:0]
+			for _, m := range s.Methods {
+				methods = append(methods, indexKey{method: strings.ToUpper(m)})
+			}
+		}
+
+		for _, key := range methods {
+			if s.Segments == nil {
+				key.anySegment = true
+				index.add(key, idx)
+				continue
+			}
+			for _, segment := range s.Segments {
+				key.segment = segment
+				index.add(key, idx)
+			}
+		}
+	}
+
+	return index
+}
+
+func (i *ruleIndex) add(key indexKey, idx int) {
+	bucket := i.buckets[key]
+	if len(bucket) > 0 && bucket[len(bucket)-1] == idx {
+		return
+	}
+	i.buckets[key] = append(bucket, idx)
+}
+
+// candidates returns the indexes of the rules that can match the request in ascending order. Rules added after the
+// index was built are always candidates.
+func (i *ruleIndex) candidates(actions map[string]string, ruleCount int) []int {
+	method := strings.ToUpper(actions["method"])
+	segment, hasSegment := firstPathSegment(actions["route"])
+
+	keys := []indexKey{{method: method, anySegment: true}, {anyMethod: true, anySegment: true}}
+	if hasSegment {
+		keys = append(keys, indexKey{method: method, segment: segment}, indexKey{anyMethod: true, segment: segment})
+	}
+
+	var candidates []int
+	for _, key := range keys {
+		candidates = append(candidates, i.buckets[key]...)
+	}
+	sort.Ints(candidates)
+
+	for idx := i.size; idx < ruleCount; idx++ {
+		candidates = append(candidates, idx)
+	}
+
+	return candidates
+}
+
+// firstPathSegment returns the first segment of the path of a request URI
+func firstPathSegment(route string) (string, bool) {
+	path := requestPath(route)
+	if !strings.HasPrefix(path, "/") {
+		return "", false
+	}
+
+	path = path[1:]
+	if idx := strings.Index(path, "/"); idx >= 0 {
+		path = path[:idx]
+	}
+
+	return path, true
+}
+
+// templateSegments returns the literal first segments of the templates, nil if any template starts with a parameter
+func templateSegments(templates []*pathTemplate) []string {
+	var segments []string
+	for _, t := range templates {
+		if !t.literalFirst {
+			return nil
+		}
+		segments = append(segments, t.firstSegment)
+	}
+
+	return segments
+}
+
+// regexSegment returns the first path segment all matches of a pattern start with, if the pattern is anchored at the
+// start and begins with a literal /segment/
+func regexSegment(pattern string) (string, bool) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", false
+	}
+	re = re.Simplify()
+	if re.Op != syntax.OpConcat || len(re.Sub) < 2 || re.Sub[0].Op != syntax.OpBeginText {
+		return "", false
+	}
+
+	var prefix []rune
+	for _, sub := range re.Sub[1:] {
+		if sub.Op != syntax.OpLiteral || sub.Flags&syntax.FoldCase != 0 {
+			break
+		}
+		prefix = append(prefix, sub.Rune...)
+	}
+
+	literal := string(prefix)
+	if !strings.HasPrefix(literal, "/") {
+		return "", false
+	}
+	end := strings.Index(literal[1:], "/")
+	if end < 0 || strings.ContainsAny(literal[:end+1], "?#") {
+		return "", false
+	}
+
+	return literal[1 : end+1], true
+}
+
+// methodScope returns the methods for a rule scope, nil if any method is permitted
+func methodScope(methods []string) []string {
+	if len(methods) == 0 || matchesMethod(methods, "") {
+		return nil
+	}
+
+	return methods
+}
+
+func (r RouteRule) scope() ruleScope {
+	s := ruleScope{Methods: methodScope(r.Method)}
+	segments := templateSegments(r.paths)
+	if len(r.paths) > 0 && segments == nil {
+		return s
+	}
+
+	for _, route := range r.regexRoutes() {
+		segment, ok := regexSegment(route)
+		if !ok {
+			return s
+		}
+		segments = append(segments, segment)
+	}
+	s.Segments = segments
+
+	return s
+}
+
+func (r ClaimRule) scope() ruleScope {
+	s := ruleScope{Methods: methodScope(r.Method)}
+	if len(r.paths) > 0 {
+		s.Segments = templateSegments(r.paths)
+	}
+
+	return s
+}
@@ -0,0 +1,109 @@
+package authorization
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ticketmaster/authentication/common"
+)
+
+func TestRegexSegment(t *testing.T) {
+	for pattern, expected := range map[string]string{
+		"^/api/v1":     "api",
+		"^/api/.*":     "api",
+		`^/api/\d+`:    "api",
+		"^/api":        "",
+		"/api/":        "",
+		"^/a|^/b/":     "",
+		"(?i)^/api/":   "",
+		`^/a\?b/`:      "",
+		"^/tenants/?x": "",
+	} {
+		segment, ok := regexSegment(pattern)
+		assert.Equal(t, expected, segment, pattern)
+		assert.Equal(t, expected != "", ok, pattern)
+	}
+}
+
+// generateRules returns a configuration with count route rules spread over methods and path segments plus a few
+// action and claim rules
+func generateRules(count int) []byte {
+	var config strings.Builder
+	config.WriteString("authorization:\n  default: deny\n  rules:\n")
+	methods := []string{"GET", "POST", "PUT", "DELETE", "*"}
+	for i := 0; i < count; i++ {
+		switch i % 4 {
+		case 0:
+			fmt.Fprintf(&config, "    - ruleType: route\n      method: \"%s\"\n      path: /service%v/items/:id\n      authorize: allow\n      role: role%v\n      origin: \".*\"\n", methods[i%5], i%50, i%7)
+		case 1:
+			fmt.Fprintf(&config, "    - ruleType: route\n      method: \"%s\"\n      routeRegex: ^/service%v/admin/.*\n      authorize: deny\n      role: role%v\n      origin: ldap\n", methods[i%5], i%50, i%7)
+		case 2:
+			route := fmt.Sprintf("^/legacy%v/", i%50)
+			if i%20 == 2 {
+				route = fmt.Sprintf("/legacy%v", i%50)
+			}
+			fmt.Fprintf(&config, "    - ruleType: route\n      method: \"%s\"\n      route: %s\n      authorize: allow\n      role: role%v\n      origin: \".*\"\n", methods[i%5], route, i%7)
+		default:
+			fmt.Fprintf(&config, "    - ruleType: claim\n      method: \"%s\"\n      path: /service%v/*\n      authorize: allow\n      claims:\n        - claim: email\n          operator: regex\n          value: \"@example\\\\.com$\"\n", methods[i%5], i%50)
+		}
+	}
+	config.WriteString("    - ruleType: action\n      action: App\\.Index\n      authorize: allow\n      role: role1\n      origin: \".*\"\n")
+
+	return []byte(config.String())
+}
+
+func TestIndexedEvaluate(t *testing.T) {
+	authorization, err := NewAuthorization(getConfigElement(generateRules(200)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unindexed := *authorization
+	unindexed.index = nil
+
+	for _, strategy := range []string{DenyOverrides, PermitOverrides, FirstApplicable, MostSpecificWins} {
+		authorization.Strategy = strategy
+		unindexed.Strategy = strategy
+		for r := 0; r < 7; r++ {
+			user := &common.User{Origin: "ldap", Username: "test", Email: "test@example.com", Roles: []string{fmt.Sprintf("role%v", r)}}
+			for _, method := range []string{"GET", "post", "DELETE", ""} {
+				for _, route := range []string{"/service3/items/42", "/service7/admin/users?x=1", "/legacy12", "/legacy12/a", "/x/legacy2", "/service9", "", "relative"} {
+					actions := map[string]string{"route": route, "method": method, "action": "App.Index"}
+					assert.Equal(t, unindexed.Evaluate(user, actions), authorization.Evaluate(user, actions), "%s %s %s", strategy, method, route)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkEvaluate(b *testing.B) {
+	authorization, err := NewAuthorization(getConfigElement(generateRules(500)))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	user := &common.User{Origin: "ldap", Username: "test", Email: "test@example.com", Roles: []string{"role3"}}
+	actions := map[string]string{"route": "/service13/items/42?expand=true", "method": "GET"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		authorization.Evaluate(user, actions)
+	}
+}
+
+func BenchmarkEvaluateUnindexed(b *testing.B) {
+	authorization, err := NewAuthorization(getConfigElement(generateRules(500)))
+	if err != nil {
+		b.Fatal(err)
+	}
+	authorization.index = nil
+
+	user := &common.User{Origin: "ldap", Username: "test", Email: "test@example.com", Roles: []string{"role3"}}
+	actions := map[string]string{"route": "/service13/items/42?expand=true", "method": "GET"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		authorization.Evaluate(user, actions)
+	}
+}
@@ -0,0 +1,94 @@
+package authorization
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// pathTemplate is a compiled route path template such as /users/:id/orders/* or /users/{id}
+type pathTemplate struct {
+	Template      string
+	regex         *regexp.Regexp
+	literalLength int
+	// firstSegment is the literal first segment of the template, empty if it is a parameter or wildcard
+	firstSegment string
+	literalFirst bool
+}
+
+// compilePathTemplate compiles a path template into an anchored regular expression. A :name or {name} segment matches
+// exactly one path segment and a trailing * or *name segment matches the remainder of the path, including nothing.
+func compilePathTemplate(template string) (*pathTemplate, error) {
+	if !strings.HasPrefix(template, "/") {
+		return nil, errors.New("path template must begin with /")
+	}
+
+	segments := strings.Split(template[1:], "/")
+	var pattern strings.Builder
+	literalLength := 0
+	firstSegment, literalFirst := "", false
+	pattern.WriteString("^")
+	for idx, segment := range segments {
+		switch {
+		case strings.HasPrefix(segment, "*"):
+			if idx != len(segments)-1 {
+				return nil, fmt.Errorf("wildcard must be the last segment of path template %s", template)
+			}
+			pattern.WriteString("(?:/.*)?")
+		case strings.HasPrefix(segment, ":") || (strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")):
+			if len(strings.Trim(segment, ":{}")) == 0 {
+				return nil, fmt.Errorf("parameter without a name in path template %s", template)
+			}
+			pattern.WriteString("/[^/]+")
+			literalLength++
+		default:
+			if strings.ContainsAny(segment, "{}*") {
+				return nil, fmt.Errorf("invalid segment %s in path template %s", segment, template)
+			}
+			pattern.WriteString("/" + regexp.QuoteMeta(segment))
+			literalLength += len(segment) + 1
+			if idx == 0 {
+				firstSegment, literalFirst = segment, true
+			}
+		}
+	}
+	pattern.WriteString("$")
+
+	regex, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return &pathTemplate{Template: template, regex: regex, literalLength: literalLength, firstSegment: firstSegment, literalFirst: literalFirst}, nil
+}
+
+// Match reports whether the path matches the template. The match length is the number of literal characters of the
+// template so that templates with fewer parameters are more specific.
+func (t pathTemplate) Match(path string) (bool, int) {
+	if !t.regex.MatchString(path) {
+		return false, 0
+	}
+
+	return true, t.literalLength
+}
+
+// requestPath returns the path component of a request route, cleaned like the path routers match so that /./admin/x
+// and //admin/x are matched as /admin/x. A trailing slash is kept. The route is expected to be percent-decoded
+// already, as the adapters pass URL.Path.
+func requestPath(route string) string {
+	if idx := strings.IndexAny(route, "?#"); idx >= 0 {
+		route = route[:idx]
+	}
+	if len(route) == 0 {
+		return route
+	}
+
+	cleaned := path.Clean("/" + route)
+	if strings.HasSuffix(route, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+
+	return cleaned
+}
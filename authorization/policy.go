@@ -0,0 +1,150 @@
+package authorization
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/ticketmaster/authentication/common"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// PolicyCase is an authorization request made by a user: a method and route, an action or both. The method defaults
+// to GET, FullName and Email are the name and email of the user and Claims stand in for the claims of the user's
+// token. Expect is allow or deny, or empty if only the decision is of interest.
+type PolicyCase struct {
+	Name     string
+	User     string
+	FullName string `yaml:"fullName"`
+	Email    string
+	Origin   string
+	Roles    []string
+	Claims   map[string]interface{}
+	Method   string
+	Route    string
+	Action   string
+	Headers  map[string]string
+	Expect   string
+}
+
+// LoadPolicyCases reads a YAML list of policy cases
+func LoadPolicyCases(path string) ([]PolicyCase, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cases []PolicyCase
+	err = yaml.UnmarshalStrict(data, &cases)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", path, err)
+	}
+
+	for idx, c := range cases {
+		if c.Expect != "" && c.Expect != "allow" && c.Expect != "deny" {
+			return nil, fmt.Errorf("error reading %s: case %v: expect must be allow or deny", path, idx)
+		}
+	}
+
+	return cases, nil
+}
+
+// RunPolicyTests runs every case of a policy test file as a subtest of t, failing the cases whose decision differs
+// from the one they expect. Each failure reports the explanation of the decision.
+func RunPolicyTests(t *testing.T, auth *Authorization, path string) {
+	cases, err := LoadPolicyCases(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.String(), func(t *testing.T) {
+			if len(c.Expect) == 0 {
+				t.Fatal("expect must be specified")
+			}
+			explanation, err := auth.CheckPolicyCase(c)
+			if err != nil {
+				t.Errorf("%v\n%s", err, explanation)
+			}
+		})
+	}
+}
+
+// CheckPolicyCase explains the decision for a case and returns an error if it differs from the expected decision
+func (a Authorization) CheckPolicyCase(c PolicyCase) (Explanation, error) {
+	explanation := a.Explain(c.NewUser(), c.Actions())
+	if len(c.Expect) > 0 && (c.Expect == "allow") != explanation.Allow {
+		return explanation, fmt.Errorf("expected %s", c.Expect)
+	}
+
+	return explanation, nil
+}
+
+// String names the case by its Name or else by the request
+func (c PolicyCase) String() string {
+	if len(c.Name) > 0 {
+		return c.Name
+	}
+
+	request := c.Actions()["method"] + " " + c.Route
+	if len(c.Action) > 0 {
+		request = c.Action
+	}
+
+	return fmt.Sprintf("%s/%s %v %s", c.Origin, c.User, c.Roles, request)
+}
+
+// NewUser returns the user making the request
+func (c PolicyCase) NewUser() *common.User {
+	user := &common.User{Origin: c.Origin, Username: c.User, Name: c.FullName, Email: c.Email, Roles: c.Roles}
+	if c.Claims != nil {
+		claims := stringKeys(c.Claims).(map[string]interface{})
+		user.Token = &jwt.Token{Claims: jwt.MapClaims(claims)}
+	}
+
+	return user
+}
+
+// Actions returns the actions map of the request, see RequestActions
+func (c PolicyCase) Actions() map[string]string {
+	header := http.Header{}
+	for name, value := range c.Headers {
+		header.Set(name, value)
+	}
+
+	method := c.Method
+	if len(method) == 0 && len(c.Route) > 0 {
+		method = http.MethodGet
+	}
+
+	return RequestActions(c.Action, c.Route, method, header)
+}
+
+// stringKeys converts the maps YAML produces to the maps of token claims
+func stringKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = stringKeys(item)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = stringKeys(item)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			list = append(list, stringKeys(item))
+		}
+		return list
+	}
+
+	return value
+}
@@ -6,63 +6,65 @@ import (
 	"strings"
 
 	"github.com/ticketmaster/authentication/common"
-	"github.com/golang/glog"
-	"github.com/mitchellh/mapstructure"
 )
 
-// ActionRule is an AuthorizationRule that permits or denies access based on the action
+// RouteRule is an AuthorizationRule that permits or denies access based on the request path and method. Path holds
+// anchored path templates matched against the path component only, RouteRegex (or the older Route) regular expressions
+// matched anywhere in the request URI.
 type RouteRule struct {
 	BaseAuthorizationRule `mapstructure:",squash"`
-	Method                string
+	Method                []string
+	Path                  []string
+	RouteRegex            []string
 	Route                 []string
+
+	paths  []*pathTemplate
+	routes []*regexp.Regexp
 }
 
 // NewRouteRule returns a new RouteRule based on the configuration provided
 func NewRouteRule(config map[interface{}]interface{}) (authorizationRule, error) {
 	r := &RouteRule{}
-	decodeErr := mapstructure.Decode(config, r)
+	decodeErr := decodeRule(config, r)
 	if decodeErr != nil {
 		return nil, decodeErr
 	}
 
-	var err []string
-	if r.Authorize != "allow" && r.Authorize != "deny" {
-		err = append(err, "authorize parameter must be specified")
-	}
-
-	if len(r.Route) == 0 {
-		err = append(err, "route parameter must be specified")
-	}
-
-	if len(r.Role) == 0 {
-		err = append(err, "role parameter must be specified")
-	}
-
-	if len(r.Origin) == 0 {
-		err = append(err, "origin parameter must be specified")
+	err := r.validate(false)
+	if len(r.Path) == 0 && len(r.RouteRegex) == 0 && len(r.Route) == 0 {
+		err = append(err, "path: one of path, routeRegex or route must be specified")
 	}
 
 	if len(r.Method) == 0 {
-		r.Method = "GET"
+		r.Method = []string{"GET"}
 	}
 
-	for idx, route := range r.Route {
-		_, e := regexp.Compile(route)
+	for idx, path := range r.Path {
+		template, e := compilePathTemplate(path)
 		if e != nil {
-			err = append(err, fmt.Sprintf("Route Index %v: %v", idx, e))
+			err = append(err, fmt.Sprintf("path[%v]: %v", idx, e))
+			continue
 		}
+		r.paths = append(r.paths, template)
 	}
 
-	_, e := regexp.Compile(r.Origin)
-	if e != nil {
-		err = append(err, e.Error())
-	}
+	routeRegexes, e := compilePatterns("routeRegex", r.RouteRegex)
+	err = append(err, e...)
+	routes, e := compilePatterns("route", r.Route)
+	err = append(err, e...)
+	r.routes = append(routeRegexes, routes...)
+	warnEmptyMatches("Route", r.routes)
 
 	if len(err) == 0 {
 		return r, nil
 	}
 
-	return nil, fmt.Errorf("errors occurred creating route rule: %v", strings.Join(err, "\n"))
+	return nil, &RuleError{Type: "route", Errors: err}
+}
+
+// Type returns the rule type name used in configuration
+func (r RouteRule) Type() string {
+	return "route"
 }
 
 // IsMatch returns if this rule is matched
@@ -73,24 +75,48 @@ func (r RouteRule) IsMatch(user *common.User, actions map[string]string) ruleMat
 		return ruleMatch{IsMatch: false}
 	}
 
-	for idx, routePattern := range r.Route {
-		rg := regexp.MustCompile(routePattern)
-		if rg.MatchString("") {
-			glog.Warningf("Route rule: %v at index %v matches an empty string. This is not permitted and the action pattern will be skipped.", routePattern, idx)
+	if !matchesMethod(r.Method, method) || !r.appliesTo(user) {
+		return ruleMatch{IsMatch: false}
+	}
+
+	var allow bool
+	if r.Authorize == "allow" {
+		allow = true
+	}
+
+	path := requestPath(route)
+	for _, template := range r.paths {
+		if ok, length := template.Match(path); ok {
+			return ruleMatch{IsMatch: true, PermitAccess: allow, MatchLength: length}
 		}
-		match := rg.FindString(route)
+	}
+
+	for _, rg := range r.routes {
+		match := rg.FindString(path)
 		if match == "" {
 			continue
 		}
 
-		if regexp.MustCompile(r.Origin).MatchString(user.Origin) && user.HasRole(r.Role) && strings.ToLower(method) == strings.ToLower(r.Method) {
-			var allow bool
-			if r.Authorize == "allow" {
-				allow = true
-			}
-			return ruleMatch{IsMatch: true, PermitAccess: allow, MatchLength: len(match)}
-		}
+		return ruleMatch{IsMatch: true, PermitAccess: allow, MatchLength: len(match)}
 	}
 
 	return ruleMatch{IsMatch: false}
 }
+
+// regexRoutes returns the RouteRegex and Route patterns
+func (r RouteRule) regexRoutes() []string {
+	routes := make([]string, 0, len(r.RouteRegex)+len(r.Route))
+	routes = append(routes, r.RouteRegex...)
+	return append(routes, r.Route...)
+}
+
+// matchesMethod returns true if the method is listed or any method is permitted with * or ANY
+func matchesMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == "*" || strings.EqualFold(m, "ANY") || strings.EqualFold(m, method) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,106 @@
+package authorization
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ticketmaster/authentication/common"
+)
+
+func TestPathTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		path     string
+		match    bool
+	}{
+		{"/test/route", "/test/route", true},
+		{"/test/route", "/evil/test/route", false},
+		{"/test/route", "/test/route/more", false},
+		{"/users/:id/orders/*", "/users/42/orders", true},
+		{"/users/:id/orders/*", "/users/42/orders/7/items", true},
+		{"/users/:id/orders/*", "/users//orders/7", false},
+		{"/users/{id}", "/users/42", true},
+		{"/users/{id}", "/users/42/orders", false},
+		{"/files/*path", "/files/a/b.txt", true},
+		{"/a.b", "/axb", false},
+	}
+	for _, test := range tests {
+		template, err := compilePathTemplate(test.template)
+		if !assert.NoError(t, err, test.template) {
+			continue
+		}
+		ok, _ := template.Match(test.path)
+		assert.Equal(t, test.match, ok, "%s %s", test.template, test.path)
+	}
+
+	for _, template := range []string{"users", "/users/*/orders", "/users/:", "/users/{id"} {
+		_, err := compilePathTemplate(template)
+		assert.Error(t, err, template)
+	}
+
+	// Literal segments are more specific than parameters
+	literal, _ := compilePathTemplate("/users/me")
+	param, _ := compilePathTemplate("/users/:id")
+	_, literalLength := literal.Match("/users/me")
+	_, paramLength := param.Match("/users/me")
+	assert.True(t, literalLength > paramLength)
+}
+
+func TestRouteRulePath(t *testing.T) {
+	rule, err := NewRouteRule(map[interface{}]interface{}{
+		"path":      []interface{}{"/users/:id/orders/*"},
+		"method":    "GET",
+		"authorize": "allow",
+		"role":      "testRole",
+		"origin":    ".*",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := &common.User{Origin: "testOrigin", Roles: []string{"testRole"}}
+	assert.True(t, rule.IsMatch(user, map[string]string{"route": "/users/42/orders?page=2", "method": "GET"}).IsMatch)
+	assert.False(t, rule.IsMatch(user, map[string]string{"route": "/evil/users/42/orders", "method": "GET"}).IsMatch)
+	assert.False(t, rule.IsMatch(user, map[string]string{"route": "/x?next=/users/42/orders", "method": "GET"}).IsMatch)
+	// Paths are cleaned before matching
+	assert.True(t, rule.IsMatch(user, map[string]string{"route": "/users/./42//orders/", "method": "GET"}).IsMatch)
+	assert.True(t, rule.IsMatch(user, map[string]string{"route": "/x/../users/42/orders", "method": "GET"}).IsMatch)
+
+	_, err = NewRouteRule(map[interface{}]interface{}{"path": []interface{}{"/users/*/orders"}, "authorize": "allow", "role": "testRole", "origin": ".*"})
+	assert.Error(t, err)
+	_, err = NewRouteRule(map[interface{}]interface{}{"authorize": "allow", "role": "testRole", "origin": ".*"})
+	assert.Error(t, err)
+}
+
+func TestRouteRuleLists(t *testing.T) {
+	rule, err := NewRouteRule(map[interface{}]interface{}{
+		"path":      []interface{}{"/myroute"},
+		"method":    []interface{}{"GET", "put", "DELETE"},
+		"authorize": "allow",
+		"role":      []interface{}{"Operator", "Administrator"},
+		"origin":    []interface{}{"foo", "bar"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operator := &common.User{Origin: "foo", Roles: []string{"Operator"}}
+	admin := &common.User{Origin: "bar", Roles: []string{"Administrator"}}
+	other := &common.User{Origin: "baz", Roles: []string{"Administrator"}}
+	for _, method := range []string{"GET", "PUT", "delete"} {
+		assert.True(t, rule.IsMatch(operator, map[string]string{"route": "/myroute", "method": method}).IsMatch, method)
+		assert.True(t, rule.IsMatch(admin, map[string]string{"route": "/myroute", "method": method}).IsMatch, method)
+		assert.False(t, rule.IsMatch(other, map[string]string{"route": "/myroute", "method": method}).IsMatch, method)
+	}
+	assert.False(t, rule.IsMatch(operator, map[string]string{"route": "/myroute", "method": "POST"}).IsMatch)
+
+	for _, wildcard := range []string{"*", "ANY"} {
+		rule, err = NewRouteRule(map[interface{}]interface{}{"path": "/myroute", "method": wildcard, "authorize": "allow", "role": "Operator", "origin": "foo"})
+		if assert.NoError(t, err) {
+			assert.True(t, rule.IsMatch(operator, map[string]string{"route": "/myroute", "method": "PATCH"}).IsMatch, wildcard)
+		}
+	}
+
+	_, err = NewRouteRule(map[interface{}]interface{}{"path": "/myroute", "authorize": "allow", "role": "Operator", "origin": []interface{}{"foo", "(bar"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,81 @@
+package authorization
+
+// Combining strategies select the deciding rule among the rules matching a request
+const (
+	// DenyOverrides lets any matching deny rule deny access, otherwise the most specific allow rule permits it
+	DenyOverrides = "deny-overrides"
+	// PermitOverrides lets any matching allow rule permit access, otherwise the most specific deny rule denies it
+	PermitOverrides = "permit-overrides"
+	// FirstApplicable lets the first matching rule in configuration order decide
+	FirstApplicable = "first-applicable"
+	// MostSpecificWins lets the rule with the longest match decide, deny rules winning ties
+	MostSpecificWins = "most-specific-wins"
+)
+
+type combiningStrategy func(matches []RuleMatch) *RuleMatch
+
+var combiningStrategies = map[string]combiningStrategy{
+	DenyOverrides:    denyOverrides,
+	PermitOverrides:  permitOverrides,
+	FirstApplicable:  firstApplicable,
+	MostSpecificWins: mostSpecificWins,
+}
+
+func denyOverrides(matches []RuleMatch) *RuleMatch {
+	if m := firstWithAccess(matches, false); m != nil {
+		return m
+	}
+
+	return mostSpecific(matches, true)
+}
+
+func permitOverrides(matches []RuleMatch) *RuleMatch {
+	if m := mostSpecific(matches, true); m != nil {
+		return m
+	}
+
+	return mostSpecific(matches, false)
+}
+
+func firstApplicable(matches []RuleMatch) *RuleMatch {
+	if len(matches) == 0 {
+		return nil
+	}
+
+	return &matches[0]
+}
+
+func mostSpecificWins(matches []RuleMatch) *RuleMatch {
+	var deciding *RuleMatch
+	for idx := range matches {
+		m := &matches[idx]
+		if deciding == nil || m.MatchLength > deciding.MatchLength || (m.MatchLength == deciding.MatchLength && deciding.PermitAccess && !m.PermitAccess) {
+			deciding = m
+		}
+	}
+
+	return deciding
+}
+
+func firstWithAccess(matches []RuleMatch, permit bool) *RuleMatch {
+	for idx := range matches {
+		if matches[idx].PermitAccess == permit {
+			return &matches[idx]
+		}
+	}
+
+	return nil
+}
+
+// mostSpecific returns the first of the longest matching rules with the access
+func mostSpecific(matches []RuleMatch, permit bool) *RuleMatch {
+	var deciding *RuleMatch
+	for idx := range matches {
+		m := &matches[idx]
+		if m.PermitAccess == permit && (deciding == nil || m.MatchLength > deciding.MatchLength) {
+			deciding = m
+		}
+	}
+
+	return deciding
+}
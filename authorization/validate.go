@@ -0,0 +1,134 @@
+package authorization
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Validate checks an authorization configuration and returns every problem found, each prefixed with its path in the
+// configuration, e.g. "rules[3].route[0]: error parsing regexp: missing closing ]: `[a-z`". Unlike NewAuthorization it
+// does not skip rules of an unknown type.
+func Validate(config map[string]interface{}) []string {
+	var err []string
+	if def, ok := config["default"]; ok && def != "allow" && def != "deny" {
+		err = append(err, "default: must be allow or deny")
+	}
+
+	if strategy, ok := config["strategy"]; ok {
+		name, _ := strategy.(string)
+		if _, ok := combiningStrategies[name]; !ok {
+			err = append(err, fmt.Sprintf("strategy: unknown authorization strategy: %v", strategy))
+		}
+	}
+
+	if _, e := decisionCacheSize(config["cache"]); e != nil {
+		err = append(err, fmt.Sprintf("cache: %v", e))
+	}
+
+	rules, ok := config["rules"].([]interface{})
+	if !ok && config["rules"] != nil {
+		return append(err, "rules: must be a list")
+	}
+
+	for idx, rule := range rules {
+		_, e := buildRule(rule)
+		for _, m := range e {
+			err = append(err, fmt.Sprintf("rules[%v]%s", idx, m))
+		}
+	}
+
+	return err
+}
+
+// buildRule creates the rule described by a rule configuration, returning its problems located relative to the rule
+func buildRule(rule interface{}) (authorizationRule, []string) {
+	ruleConfig, ok := rule.(map[interface{}]interface{})
+	if !ok {
+		return nil, []string{": must be a map"}
+	}
+
+	ruleType, _ := ruleConfig["ruleType"].(string)
+	if len(ruleType) == 0 {
+		return nil, []string{".ruleType: must be specified"}
+	}
+
+	constructor, ok := supportedRules[ruleType]
+	if !ok {
+		return nil, []string{fmt.Sprintf(".ruleType: unknown rule type: %s", ruleType)}
+	}
+
+	built, e := constructor(ruleConfig)
+	if e == nil {
+		return built, nil
+	}
+
+	ruleErr, ok := e.(*RuleError)
+	if !ok {
+		return nil, []string{fmt.Sprintf(": %v", e)}
+	}
+
+	var err []string
+	for _, m := range ruleErr.Errors {
+		err = append(err, "."+m)
+	}
+
+	return nil, err
+}
+
+// DeadRules returns warnings for the parts of valid rules that can never apply: origin patterns matching none of the
+// origins and roles that none of the authentication clients grant. Role checks are skipped if roles is nil, meaning
+// the roles users can hold are not known.
+func DeadRules(config map[string]interface{}, origins []string, roles []string) []string {
+	var warnings []string
+	rules, _ := config["rules"].([]interface{})
+	for idx, rule := range rules {
+		built, _ := buildRule(rule)
+		base, ok := built.(interface{ baseRule() BaseAuthorizationRule })
+		if !ok {
+			continue
+		}
+		r := base.baseRule()
+
+		for originIdx, pattern := range r.Origin {
+			if !matchesAny(pattern, origins) {
+				warnings = append(warnings, fmt.Sprintf("rules[%v].origin[%v]: %s matches no configured origin", idx, originIdx, pattern))
+			}
+		}
+
+		if roles == nil {
+			continue
+		}
+		for roleIdx, role := range r.Role {
+			if !contains(roles, role) {
+				warnings = append(warnings, fmt.Sprintf("rules[%v].role[%v]: no authentication client grants role %s", idx, roleIdx, role))
+			}
+		}
+	}
+
+	return warnings
+}
+
+func matchesAny(pattern string, values []string) bool {
+	rg, err := regexp.Compile(pattern)
+	if err != nil {
+		return false
+	}
+
+	for _, value := range values {
+		if rg.MatchString(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,85 @@
+package authorization
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	assert.Nil(t, Validate(getConfigElement([]byte(`
+authorization:
+  default: deny
+  rules:
+    - ruleType: claim
+      authorize: allow
+      claims:
+        - claim: groups
+          value: admins
+`))))
+
+	assert.Equal(t, []string{
+		"default: must be allow or deny",
+		"cache: invalid authorization cache size: -1",
+		"rules: must be a list",
+	}, Validate(getConfigElement([]byte(`
+authorization:
+  default: Deny
+  cache:
+    size: -1
+  rules:
+    ruleType: route
+`))))
+
+	assert.Equal(t, []string{
+		"rules[0]: must be a map",
+		"rules[1].ruleType: must be specified",
+		"rules[2].claims[0]: unknown operator: like",
+		"rules[3].path[0]: invalid segment {id in path template /api/{id",
+		"rules[4].expression: expected ',' but found end of expression at position 23",
+	}, Validate(getConfigElement([]byte(`
+authorization:
+  rules:
+    - route
+    - authorize: allow
+    - ruleType: claim
+      authorize: allow
+      claims:
+        - claim: groups
+          operator: like
+    - ruleType: route
+      path: /api/{id
+      authorize: allow
+      role: admin
+      origin: ".*"
+    - ruleType: expression
+      authorize: allow
+      expression: "has(user.claims.groups"
+`))))
+}
+
+func TestDeadRules(t *testing.T) {
+	config := getConfigElement([]byte(`
+authorization:
+  rules:
+    - ruleType: route
+      path: /api/*
+      authorize: allow
+      role: [admin, auditor]
+      origin: ["^corp$", partner]
+    - ruleType: expression
+      authorize: allow
+      expression: "true"
+    - ruleType: route
+      path: /api/{id
+`))
+
+	assert.Equal(t, []string{
+		"rules[0].origin[1]: partner matches no configured origin",
+		"rules[0].role[1]: no authentication client grants role auditor",
+	}, DeadRules(config, []string{"corp"}, []string{"admin"}))
+	assert.Equal(t, []string{
+		"rules[0].origin[0]: ^corp$ matches no configured origin",
+		"rules[0].origin[1]: partner matches no configured origin",
+	}, DeadRules(config, []string{"corporate"}, nil))
+}
@@ -8,6 +8,11 @@ type Client interface {
 	ValidateCredentials(username string, password string) (*common.User, error)
 }
 
+// RoleLister is implemented by clients that know every role their users can hold
+type RoleLister interface {
+	Roles() []string
+}
+
 // ClientConstructor is a function definition for client constructors
 type ClientConstructor func(map[interface{}]interface{}) (Client, error)
 
@@ -17,6 +22,8 @@ func init() {
 	SupportedClients = make(map[string]ClientConstructor)
 	RegisterSupportedClient("ldap", NewLdapClient)
 	RegisterSupportedClient("memory", NewMemoryClient)
+	RegisterSupportedClient("file", NewFileClient)
+	RegisterSupportedClient("sql", NewSQLClient)
 }
 
 // RegisterSupportedClient registers a client for use
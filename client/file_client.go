@@ -0,0 +1,293 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/mitchellh/mapstructure"
+	"github.com/ticketmaster/authentication/common"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// FileClient represents a user store read from an Apache htpasswd file (with an optional groups file for roles) or a
+// JSON/YAML user database. The files are reloaded when they change on disk.
+type FileClient struct {
+	Origin                  string
+	Path                    string
+	Format                  string
+	GroupsPath              string
+	AllowPlaintextPasswords bool
+
+	mutex    sync.RWMutex
+	users    []*configUser
+	versions map[string]fileVersion
+}
+
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewFileClient creates a new client from the specified configuration
+func NewFileClient(config map[interface{}]interface{}) (Client, error) {
+	path, ok := config["path"].(string)
+	if !ok {
+		return nil, errors.New("path must be specified in configuration")
+	}
+
+	origin, ok := config["origin"].(string)
+	if !ok {
+		origin = "file"
+	}
+
+	format, ok := config["format"].(string)
+	if !ok {
+		format = formatFromExtension(path)
+	}
+	if format != "htpasswd" && format != "json" && format != "yaml" {
+		return nil, fmt.Errorf("unsupported user file format: %s", format)
+	}
+
+	groupsPath, ok := config["groupsPath"].(string)
+	if !ok {
+		groupsPath = ""
+	}
+
+	allowPlaintext, ok := config["allowPlaintextPasswords"].(bool)
+	if !ok {
+		allowPlaintext = false
+	}
+
+	c := &FileClient{Origin: origin, Path: path, Format: format, GroupsPath: groupsPath, AllowPlaintextPasswords: allowPlaintext}
+	err := c.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func formatFromExtension(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".yaml", ".yml":
+		return "yaml"
+	default:
+		return "htpasswd"
+	}
+}
+
+// Reload reads the user and groups files from disk, replacing the current users if they are valid
+func (c *FileClient) Reload() error {
+	versions := make(map[string]fileVersion)
+	for _, p := range c.paths() {
+		v, err := statFile(p)
+		if err != nil {
+			return err
+		}
+		versions[p] = v
+	}
+
+	data, err := ioutil.ReadFile(c.Path)
+	if err != nil {
+		return err
+	}
+
+	var users []*configUser
+	switch c.Format {
+	case "htpasswd":
+		users, err = parseHtpasswd(data)
+	case "json":
+		users, err = parseJSONUsers(data)
+	case "yaml":
+		users, err = parseYAMLUsers(data)
+	}
+	if err != nil {
+		return fmt.Errorf("error reading %s: %v", c.Path, err)
+	}
+
+	if len(c.GroupsPath) > 0 {
+		groupData, err := ioutil.ReadFile(c.GroupsPath)
+		if err != nil {
+			return err
+		}
+		err = applyGroups(users, groupData)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %v", c.GroupsPath, err)
+		}
+	}
+
+	for _, user := range users {
+		err := user.validate(c.AllowPlaintextPasswords)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.mutex.Lock()
+	c.users = users
+	c.versions = versions
+	c.mutex.Unlock()
+	glog.V(2).Infof("Loaded %v users from %s", len(users), c.Path)
+	return nil
+}
+
+func (c *FileClient) paths() []string {
+	if len(c.GroupsPath) > 0 {
+		return []string{c.Path, c.GroupsPath}
+	}
+
+	return []string{c.Path}
+}
+
+func statFile(path string) (fileVersion, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileVersion{}, err
+	}
+
+	return fileVersion{info.ModTime(), info.Size()}, nil
+}
+
+// reloadIfChanged reloads the files if any of them has changed since they were last read. Errors are logged and the previous users are kept.
+func (c *FileClient) reloadIfChanged() {
+	c.mutex.RLock()
+	changed := false
+	for _, p := range c.paths() {
+		v, err := statFile(p)
+		if err != nil || v != c.versions[p] {
+			changed = true
+			break
+		}
+	}
+	c.mutex.RUnlock()
+
+	if !changed {
+		return
+	}
+
+	err := c.Reload()
+	if err != nil {
+		glog.Errorf("error reloading users from %s, keeping previous users: %v", c.Path, err)
+	}
+}
+
+// ValidateCredentials takes a set of credentials and returns a User struct if the credentials are valid
+func (c *FileClient) ValidateCredentials(username string, password string) (*common.User, error) {
+	c.reloadIfChanged()
+
+	c.mutex.RLock()
+	memory := MemoryClient{c.Origin, c.users, c.AllowPlaintextPasswords}
+	c.mutex.RUnlock()
+
+	return memory.ValidateCredentials(username, password)
+}
+
+// Roles returns the roles held by the users currently loaded from the files
+func (c *FileClient) Roles() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return MemoryClient{Users: c.users}.Roles()
+}
+
+// GetOrigin returns the origin for this client
+func (c *FileClient) GetOrigin() string {
+	return c.Origin
+}
+
+func parseHtpasswd(data []byte) ([]*configUser, error) {
+	var users []*configUser
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(text, ":", 2)
+		if len(parts) != 2 || len(parts[0]) == 0 {
+			return nil, fmt.Errorf("line %v: expected username:hash", line)
+		}
+
+		// Only hashes of a registered PasswordHasher are accepted: plaintext, crypt, {SHA} and $apr1$ entries are rejected
+		if _, err := getPasswordHasher(parts[1]); err != nil {
+			return nil, fmt.Errorf("line %v: unsupported password hash for user %s", line, parts[0])
+		}
+		users = append(users, &configUser{Username: parts[0], PasswordHash: parts[1]})
+	}
+
+	return users, scanner.Err()
+}
+
+func applyGroups(users []*configUser, data []byte) error {
+	byName := make(map[string]*configUser)
+	for _, user := range users {
+		byName[user.Username] = user
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(text, ":", 2)
+		if len(parts) != 2 || len(strings.TrimSpace(parts[0])) == 0 {
+			return fmt.Errorf("line %v: expected group: user1 user2", line)
+		}
+
+		group := strings.TrimSpace(parts[0])
+		for _, username := range strings.Fields(parts[1]) {
+			if user, ok := byName[username]; ok {
+				user.Roles = append(user.Roles, group)
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+func parseJSONUsers(data []byte) ([]*configUser, error) {
+	var document struct {
+		Users []*configUser
+	}
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		err := json.Unmarshal(data, &document.Users)
+		return document.Users, err
+	}
+
+	err := json.Unmarshal(data, &document)
+	return document.Users, err
+}
+
+func parseYAMLUsers(data []byte) ([]*configUser, error) {
+	var document interface{}
+	err := yaml.Unmarshal(data, &document)
+	if err != nil {
+		return nil, err
+	}
+
+	if m, ok := document.(map[interface{}]interface{}); ok {
+		document = m["users"]
+	}
+
+	var users []*configUser
+	err = mapstructure.Decode(document, &users)
+	return users, err
+}
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testBcryptHash = "$2a$10$K85mugw/uODD2WKvAmftU.ae/3YC7qEFJdNJFtKNdYSocY0aW6Hwa"
+
+func writeTestFile(t *testing.T, path string, content string, modTime time.Time) {
+	err := ioutil.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chtimes(path, modTime, modTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileClientHtpasswd(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	passwd := filepath.Join(dir, "users.htpasswd")
+	groups := filepath.Join(dir, "groups")
+	now := time.Now()
+	writeTestFile(t, passwd, fmt.Sprintf("# service accounts\ntest:%s\ntest2:%s\n", testBcryptHash, testBcryptHash), now)
+	writeTestFile(t, groups, "testRole: test test2\ntestRole2: test\n", now)
+
+	c, err := NewFileClient(map[interface{}]interface{}{"origin": "testOrigin", "path": passwd, "groupsPath": groups})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "testOrigin", c.GetOrigin())
+	assert.Equal(t, "htpasswd", c.(*FileClient).Format)
+
+	u, err := c.ValidateCredentials("test", "testpass")
+	if err != nil {
+		t.Error(err)
+	} else {
+		assert.Equal(t, "testOrigin", u.Origin)
+		assert.Equal(t, []string{"testRole", "testRole2"}, u.Roles)
+	}
+
+	_, err = c.ValidateCredentials("test", "invalidpass")
+	assert.EqualError(t, err, "invalid credentials")
+
+	// Rotating the file removes test2 without recreating the client
+	writeTestFile(t, passwd, fmt.Sprintf("test:%s\n", testBcryptHash), now.Add(time.Second))
+	_, err = c.ValidateCredentials("test2", "testpass")
+	assert.EqualError(t, err, "invalid credentials")
+
+	// An invalid file is not loaded and the previous users are kept
+	writeTestFile(t, passwd, "test:{SHA}abc\n", now.Add(2*time.Second))
+	_, err = c.ValidateCredentials("test", "testpass")
+	assert.NoError(t, err)
+
+	// Plaintext and MD5 entries are rejected instead of being loaded as passwords
+	for _, entry := range []string{"testpass", "$apr1$salt$hash", "abJnggxhB/yWI"} {
+		_, err = parseHtpasswd([]byte("# service accounts\ntest:" + entry + "\n"))
+		assert.EqualError(t, err, "line 2: unsupported password hash for user test")
+	}
+}
+
+func TestFileClientDatabase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	jsonPath := filepath.Join(dir, "users.json")
+	writeTestFile(t, jsonPath, fmt.Sprintf(`{"users": [{"username": "test", "passwordHash": "%s", "name": "My Name", "roles": ["testRole"]}]}`, testBcryptHash), time.Now())
+	yamlPath := filepath.Join(dir, "users.yaml")
+	writeTestFile(t, yamlPath, fmt.Sprintf("users:\n  - username: test\n    passwordHash: \"%s\"\n    name: My Name\n    roles:\n      - testRole\n", testBcryptHash), time.Now())
+
+	for _, path := range []string{jsonPath, yamlPath} {
+		c, err := NewFileClient(map[interface{}]interface{}{"path": path})
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		assert.Equal(t, "file", c.GetOrigin())
+		u, err := c.ValidateCredentials("test", "testpass")
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		assert.Equal(t, "My Name", u.Name)
+		assert.Equal(t, []string{"testRole"}, u.Roles)
+	}
+
+	_, err = NewFileClient(map[interface{}]interface{}{})
+	assert.EqualError(t, err, "path must be specified in configuration")
+
+	_, err = NewFileClient(map[interface{}]interface{}{"path": jsonPath, "format": "xml"})
+	assert.EqualError(t, err, "unsupported user file format: xml")
+}
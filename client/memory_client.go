@@ -1,28 +1,29 @@
 package client
 
 import (
+	"crypto/subtle"
 	"errors"
+	"fmt"
 
-	"github.com/ticketmaster/authentication/common"
 	"github.com/mitchellh/mapstructure"
+	"github.com/ticketmaster/authentication/common"
 )
 
-// MemoryClient represents an in-memory user authentication store. This is highly insecure (plaintext passwords) and should be used for TESTING ONLY.
+// MemoryClient represents an in-memory user authentication store. Users should carry a PasswordHash in a PHC or modular
+// crypt format (see HashPassword). Plaintext passwords are only accepted when AllowPlaintextPasswords is set and should be used for TESTING ONLY.
 type MemoryClient struct {
-	Origin string
-	Users  []*configUser
+	Origin                  string
+	Users                   []*configUser
+	AllowPlaintextPasswords bool
 }
 
 type configUser struct {
-	Name     string
-	Username string
-	Password string
-	Email    string
-	Roles    []string
-}
-
-func init() {
-
+	Name         string
+	Username     string
+	Password     string
+	PasswordHash string
+	Email        string
+	Roles        []string
 }
 
 // NewMemoryClient creates a new client from the specified configuration
@@ -32,6 +33,11 @@ func NewMemoryClient(config map[interface{}]interface{}) (Client, error) {
 		origin = "memory"
 	}
 
+	allowPlaintext, ok := config["allowPlaintextPasswords"].(bool)
+	if !ok {
+		allowPlaintext = false
+	}
+
 	var users []*configUser
 	u, ok := config["users"]
 	if ok {
@@ -41,13 +47,40 @@ func NewMemoryClient(config map[interface{}]interface{}) (Client, error) {
 		}
 	}
 
-	return &MemoryClient{origin, users}, nil
+	for _, user := range users {
+		err := user.validate(allowPlaintext)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &MemoryClient{origin, users, allowPlaintext}, nil
+}
+
+func (u configUser) validate(allowPlaintext bool) error {
+	if len(u.PasswordHash) > 0 {
+		_, err := getPasswordHasher(u.PasswordHash)
+		if err != nil {
+			return fmt.Errorf("user %s: %v", u.Username, err)
+		}
+		return nil
+	}
+
+	if len(u.Password) > 0 && !allowPlaintext {
+		return fmt.Errorf("user %s: plaintext passwords require allowPlaintextPasswords to be enabled", u.Username)
+	}
+
+	return nil
 }
 
 // ValidateCredentials takes a set of credentials and returns a User struct if the credentials are valid
 func (c MemoryClient) ValidateCredentials(username string, password string) (*common.User, error) {
 	user := c.getUser(username)
-	if user == nil || user.Password != password {
+	if user == nil {
+		verifyDummyPassword(c.sampleHash(), password)
+		return nil, errors.New("invalid credentials")
+	}
+	if !c.verifyPassword(user, password) {
 		return nil, errors.New("invalid credentials")
 	}
 
@@ -56,6 +89,30 @@ func (c MemoryClient) ValidateCredentials(username string, password string) (*co
 	return &newUser, nil
 }
 
+func (c MemoryClient) verifyPassword(user *configUser, password string) bool {
+	if len(user.PasswordHash) > 0 {
+		ok, err := VerifyPassword(user.PasswordHash, password)
+		return err == nil && ok
+	}
+
+	if !c.AllowPlaintextPasswords || len(user.Password) == 0 {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(user.Password), []byte(password)) == 1
+}
+
+// sampleHash returns the password hash of the first user that has one
+func (c MemoryClient) sampleHash() string {
+	for _, user := range c.Users {
+		if len(user.PasswordHash) > 0 {
+			return user.PasswordHash
+		}
+	}
+
+	return ""
+}
+
 func (c MemoryClient) getUser(username string) *configUser {
 	for _, user := range c.Users {
 		if user.Username == username {
@@ -66,6 +123,22 @@ func (c MemoryClient) getUser(username string) *configUser {
 	return nil
 }
 
+// Roles returns the roles held by the configured users
+func (c MemoryClient) Roles() []string {
+	var roles []string
+	seen := make(map[string]bool)
+	for _, user := range c.Users {
+		for _, role := range user.Roles {
+			if !seen[role] {
+				seen[role] = true
+				roles = append(roles, role)
+			}
+		}
+	}
+
+	return roles
+}
+
 // GetOrigin returns the origin for this client
 func (c MemoryClient) GetOrigin() string {
 	return c.Origin
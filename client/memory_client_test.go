@@ -1,6 +1,7 @@
 package client
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -10,6 +11,7 @@ var validMemoryConfiguration = []byte(`
 authenticationClient:
   - provider: memory
     origin: testOrigin
+    allowPlaintextPasswords: true
     users:
         - username: test
           password: testpass
@@ -24,6 +26,28 @@ authenticationClient:
           email: test2@test.com
 `)
 
+var hashedMemoryConfiguration = []byte(`
+authenticationClient:
+  - provider: memory
+    origin: testOrigin
+    users:
+        - username: test
+          passwordHash: $2a$10$K85mugw/uODD2WKvAmftU.ae/3YC7qEFJdNJFtKNdYSocY0aW6Hwa
+          name: My Name
+        - username: test2
+          passwordHash: $argon2id$v=19$m=65536,t=3,p=2$DM994MXQJyPEehm/k1Louw$kp7RwfhvThov901pQrKub5LDNnvxCxeCRJNFALPQrxg
+          name: My Name2
+`)
+
+var plaintextWithoutOptInConfiguration = []byte(`
+authenticationClient:
+  - provider: memory
+    origin: testOrigin
+    users:
+        - username: test
+          password: testpass
+`)
+
 func TestNewMemoryClient(t *testing.T) {
 	c, err := NewMemoryClient(GetConfigElement(validMemoryConfiguration))
 	if err != nil {
@@ -81,3 +105,43 @@ func TestMemoryValidateCredentials(t *testing.T) {
 	assert.Equal(t, "test2@test.com", u.Email)
 	assert.Equal(t, 0, len(u.Roles))
 }
+
+func TestMemoryValidateHashedCredentials(t *testing.T) {
+	c, err := NewMemoryClient(GetConfigElement(hashedMemoryConfiguration))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	u, err := c.ValidateCredentials("test", "testpass")
+	if err != nil {
+		t.Error(err)
+	} else {
+		assert.Equal(t, "My Name", u.Name)
+	}
+
+	u, err = c.ValidateCredentials("test2", "testpass")
+	if err != nil {
+		t.Error(err)
+	} else {
+		assert.Equal(t, "My Name2", u.Name)
+	}
+
+	_, err = c.ValidateCredentials("test", "invalidpass")
+	assert.EqualError(t, err, "invalid credentials")
+
+	_, err = c.ValidateCredentials("unknown", "testpass")
+	assert.EqualError(t, err, "invalid credentials")
+	// Unknown users are checked against the hash of a configured user so they take as long as a wrong password
+	assert.True(t, strings.HasPrefix(c.(*MemoryClient).sampleHash(), "$2a$10$"))
+	assert.Equal(t, "", MemoryClient{}.sampleHash())
+}
+
+func TestMemoryPlaintextRequiresOptIn(t *testing.T) {
+	_, err := NewMemoryClient(GetConfigElement(plaintextWithoutOptInConfiguration))
+	assert.EqualError(t, err, "user test: plaintext passwords require allowPlaintextPasswords to be enabled")
+
+	c := &MemoryClient{Origin: "testOrigin", Users: []*configUser{{Username: "test", Password: "testpass"}}}
+	_, err = c.ValidateCredentials("test", "testpass")
+	assert.EqualError(t, err, "invalid credentials")
+}
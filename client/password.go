@@ -0,0 +1,278 @@
+package client
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// PasswordHasher creates and verifies password hashes in a PHC or modular crypt string format
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(encodedHash string, password string) (bool, error)
+}
+
+// DefaultPasswordHasher is the algorithm used by HashPassword when none is specified
+const DefaultPasswordHasher = "argon2id"
+
+var supportedHashers map[string]PasswordHasher
+
+func init() {
+	supportedHashers = make(map[string]PasswordHasher)
+	RegisterPasswordHasher("bcrypt", bcryptHasher{cost: bcrypt.DefaultCost}, "2a", "2b", "2y")
+	RegisterPasswordHasher("argon2id", argon2idHasher{memory: 64 * 1024, time: 3, threads: 2, keyLength: 32})
+	RegisterPasswordHasher("scrypt", scryptHasher{logN: 15, r: 8, p: 1, keyLength: 32})
+	RegisterPasswordHasher("pbkdf2-sha256", pbkdf2Hasher{name: "pbkdf2-sha256", hash: sha256.New, iterations: 310000, keyLength: 32})
+	RegisterPasswordHasher("pbkdf2-sha512", pbkdf2Hasher{name: "pbkdf2-sha512", hash: sha512.New, iterations: 120000, keyLength: 64})
+}
+
+// RegisterPasswordHasher registers a password hasher for the hash identifier (the first `$` delimited segment) and any aliases
+func RegisterPasswordHasher(identifier string, hasher PasswordHasher, aliases ...string) {
+	supportedHashers[identifier] = hasher
+	for _, alias := range aliases {
+		supportedHashers[alias] = hasher
+	}
+}
+
+// HashPassword hashes a password with the named algorithm. If algorithm is empty DefaultPasswordHasher is used.
+func HashPassword(algorithm string, password string) (string, error) {
+	if algorithm == "" {
+		algorithm = DefaultPasswordHasher
+	}
+
+	hasher, ok := supportedHashers[algorithm]
+	if !ok {
+		return "", fmt.Errorf("unsupported password hash algorithm: %s", algorithm)
+	}
+
+	return hasher.Hash(password)
+}
+
+// VerifyPassword verifies a password against an encoded hash in constant time
+func VerifyPassword(encodedHash string, password string) (bool, error) {
+	hasher, err := getPasswordHasher(encodedHash)
+	if err != nil {
+		return false, err
+	}
+
+	return hasher.Verify(encodedHash, password)
+}
+
+var dummyHash struct {
+	once sync.Once
+	hash string
+}
+
+// verifyDummyPassword verifies the password against like, the stored hash of another user of the same client, so that
+// looking up an unknown user takes as long as checking a wrong password. The outcome is ignored. If like is not a
+// supported hash a fixed hash of the DefaultPasswordHasher is used instead.
+func verifyDummyPassword(like string, password string) {
+	if _, err := getPasswordHasher(like); err != nil {
+		dummyHash.once.Do(func() {
+			dummyHash.hash, _ = HashPassword("", "dummy password")
+		})
+		like = dummyHash.hash
+	}
+	VerifyPassword(like, password)
+}
+
+func getPasswordHasher(encodedHash string) (PasswordHasher, error) {
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) < 3 || parts[0] != "" {
+		return nil, errors.New("password hash is not in a recognized format")
+	}
+
+	hasher, ok := supportedHashers[parts[1]]
+	if !ok {
+		return nil, fmt.Errorf("unsupported password hash algorithm: %s", parts[1])
+	}
+
+	return hasher, nil
+}
+
+func generateSalt(length int) ([]byte, error) {
+	salt := make([]byte, length)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	return salt, nil
+}
+
+// parsePHC splits a `$id$params$salt$hash` string, optionally with a `v=` segment after the identifier
+func parsePHC(encodedHash string, identifier string) (params map[string]int, salt []byte, key []byte, err error) {
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) == 6 && strings.HasPrefix(parts[2], "v=") {
+		parts = append(parts[:2], parts[3:]...)
+	}
+	if len(parts) != 5 || parts[1] != identifier {
+		return nil, nil, nil, fmt.Errorf("invalid %s hash format", identifier)
+	}
+
+	params = make(map[string]int)
+	for _, p := range strings.Split(parts[2], ",") {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 {
+			return nil, nil, nil, fmt.Errorf("invalid %s hash parameter: %s", identifier, p)
+		}
+		v, e := strconv.Atoi(kv[1])
+		if e != nil {
+			return nil, nil, nil, fmt.Errorf("invalid %s hash parameter: %s", identifier, p)
+		}
+		params[kv[0]] = v
+	}
+
+	salt, err = base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	key, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return params, salt, key, nil
+}
+
+func encodePHC(identifier string, params string, salt []byte, key []byte) string {
+	return fmt.Sprintf("$%s$%s$%s$%s", identifier, params, base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+type bcryptHasher struct {
+	cost int
+}
+
+func (h bcryptHasher) Hash(password string) (string, error) {
+	b, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+func (h bcryptHasher) Verify(encodedHash string, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+type argon2idHasher struct {
+	memory    uint32
+	time      uint32
+	threads   uint8
+	keyLength uint32
+}
+
+func (h argon2idHasher) Hash(password string) (string, error) {
+	salt, err := generateSalt(16)
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.time, h.memory, h.threads, h.keyLength)
+	params := fmt.Sprintf("v=%d$m=%d,t=%d,p=%d", argon2.Version, h.memory, h.time, h.threads)
+	return encodePHC("argon2id", params, salt, key), nil
+}
+
+func (h argon2idHasher) Verify(encodedHash string, password string) (bool, error) {
+	params, salt, key, err := parsePHC(encodedHash, "argon2id")
+	if err != nil {
+		return false, err
+	}
+	if params["m"] <= 0 || params["t"] <= 0 || params["p"] <= 0 || params["p"] > 255 {
+		return false, errors.New("invalid argon2id hash parameters")
+	}
+
+	other := argon2.IDKey([]byte(password), salt, uint32(params["t"]), uint32(params["m"]), uint8(params["p"]), uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+type scryptHasher struct {
+	logN      int
+	r         int
+	p         int
+	keyLength int
+}
+
+func (h scryptHasher) Hash(password string) (string, error) {
+	salt, err := generateSalt(16)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := scrypt.Key([]byte(password), salt, 1<<uint(h.logN), h.r, h.p, h.keyLength)
+	if err != nil {
+		return "", err
+	}
+
+	return encodePHC("scrypt", fmt.Sprintf("ln=%d,r=%d,p=%d", h.logN, h.r, h.p), salt, key), nil
+}
+
+func (h scryptHasher) Verify(encodedHash string, password string) (bool, error) {
+	params, salt, key, err := parsePHC(encodedHash, "scrypt")
+	if err != nil {
+		return false, err
+	}
+	if params["ln"] <= 0 || params["ln"] >= 32 {
+		return false, errors.New("invalid scrypt hash parameters")
+	}
+
+	other, err := scrypt.Key([]byte(password), salt, 1<<uint(params["ln"]), params["r"], params["p"], len(key))
+	if err != nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+type pbkdf2Hasher struct {
+	name       string
+	hash       func() hash.Hash
+	iterations int
+	keyLength  int
+}
+
+func (h pbkdf2Hasher) Hash(password string) (string, error) {
+	salt, err := generateSalt(16)
+	if err != nil {
+		return "", err
+	}
+
+	key := pbkdf2.Key([]byte(password), salt, h.iterations, h.keyLength, h.hash)
+	return encodePHC(h.name, fmt.Sprintf("i=%d", h.iterations), salt, key), nil
+}
+
+func (h pbkdf2Hasher) Verify(encodedHash string, password string) (bool, error) {
+	params, salt, key, err := parsePHC(encodedHash, h.name)
+	if err != nil {
+		return false, err
+	}
+	if params["i"] <= 0 {
+		return false, fmt.Errorf("invalid %s hash parameters", h.name)
+	}
+
+	other := pbkdf2.Key([]byte(password), salt, params["i"], len(key), h.hash)
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashPassword(t *testing.T) {
+	for _, algorithm := range []string{"", "bcrypt", "argon2id", "scrypt", "pbkdf2-sha256", "pbkdf2-sha512"} {
+		hash, err := HashPassword(algorithm, "testpass")
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		ok, err := VerifyPassword(hash, "testpass")
+		assert.NoError(t, err, algorithm)
+		assert.True(t, ok, algorithm)
+
+		ok, err = VerifyPassword(hash, "invalidpass")
+		assert.NoError(t, err, algorithm)
+		assert.False(t, ok, algorithm)
+	}
+
+	_, err := HashPassword("md5", "testpass")
+	assert.EqualError(t, err, "unsupported password hash algorithm: md5")
+}
+
+func TestVerifyPassword(t *testing.T) {
+	ok, err := VerifyPassword("$2a$10$K85mugw/uODD2WKvAmftU.ae/3YC7qEFJdNJFtKNdYSocY0aW6Hwa", "testpass")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = VerifyPassword("$argon2id$v=19$m=65536,t=3,p=2$DM994MXQJyPEehm/k1Louw$kp7RwfhvThov901pQrKub5LDNnvxCxeCRJNFALPQrxg", "testpass")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = VerifyPassword("testpass", "testpass")
+	assert.EqualError(t, err, "password hash is not in a recognized format")
+
+	_, err = VerifyPassword("$md5$abc$def", "testpass")
+	assert.EqualError(t, err, "unsupported password hash algorithm: md5")
+
+	_, err = VerifyPassword("$scrypt$ln=15$abc", "testpass")
+	assert.EqualError(t, err, "invalid scrypt hash format")
+}
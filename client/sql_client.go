@@ -0,0 +1,161 @@
+package client
+
+import (
+	"database/sql"
+	"errors"
+	"sync/atomic"
+
+	"github.com/ticketmaster/authentication/common"
+)
+
+// SQLClient represents a user store in a database/sql database. PasswordQuery takes the username as its only parameter
+// and returns the password hash, optionally followed by the name and email. RolesQuery takes the username and returns one role per row.
+type SQLClient struct {
+	Origin        string
+	PasswordQuery string
+	RolesQuery    string
+	DB            *sql.DB
+
+	// lastHash is the password hash most recently read, which unknown users are checked against
+	lastHash *atomic.Value
+}
+
+// NewSQLClient creates a new client from the specified configuration
+func NewSQLClient(config map[interface{}]interface{}) (Client, error) {
+	driver, ok := config["driver"].(string)
+	if !ok {
+		return nil, errors.New("driver must be specified in configuration")
+	}
+
+	dsn, ok := config["dsn"].(string)
+	if !ok {
+		return nil, errors.New("dsn must be specified in configuration")
+	}
+
+	origin, ok := config["origin"].(string)
+	if !ok {
+		origin = "sql"
+	}
+
+	passwordQuery, ok := config["passwordQuery"].(string)
+	if !ok {
+		return nil, errors.New("passwordQuery must be specified in configuration")
+	}
+
+	rolesQuery, ok := config["rolesQuery"].(string)
+	if !ok {
+		rolesQuery = ""
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SQLClient{Origin: origin, PasswordQuery: passwordQuery, RolesQuery: rolesQuery, DB: db, lastHash: &atomic.Value{}}, nil
+}
+
+// sampleHash returns the password hash of the user most recently looked up, empty if there was none
+func (c SQLClient) sampleHash() string {
+	if c.lastHash == nil {
+		return ""
+	}
+
+	hash, _ := c.lastHash.Load().(string)
+	return hash
+}
+
+// Close closes the database connection pool
+func (c SQLClient) Close() error {
+	return c.DB.Close()
+}
+
+// ValidateCredentials takes a set of credentials and returns a User struct if the credentials are valid
+func (c SQLClient) ValidateCredentials(username string, password string) (*common.User, error) {
+	user := common.User{Origin: c.GetOrigin(), Username: username}
+	passwordHash, err := c.queryUser(&user)
+	if err == sql.ErrNoRows {
+		verifyDummyPassword(c.sampleHash(), password)
+		return nil, errors.New("invalid credentials")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c.lastHash != nil {
+		c.lastHash.Store(passwordHash)
+	}
+	ok, err := VerifyPassword(passwordHash, password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("invalid credentials")
+	}
+
+	if len(c.RolesQuery) == 0 {
+		return &user, nil
+	}
+
+	rows, err := c.DB.Query(c.RolesQuery, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var role string
+		err = rows.Scan(&role)
+		if err != nil {
+			return nil, err
+		}
+		user.Roles = append(user.Roles, role)
+	}
+
+	return &user, rows.Err()
+}
+
+func (c SQLClient) queryUser(user *common.User) (string, error) {
+	rows, err := c.DB.Query(c.PasswordQuery, user.Username)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if rows.Err() != nil {
+			return "", rows.Err()
+		}
+		return "", sql.ErrNoRows
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+
+	var passwordHash string
+	var name, email sql.NullString
+	switch len(columns) {
+	case 1:
+		err = rows.Scan(&passwordHash)
+	case 2:
+		err = rows.Scan(&passwordHash, &name)
+	case 3:
+		err = rows.Scan(&passwordHash, &name, &email)
+	default:
+		return "", errors.New("passwordQuery must return the password hash and optionally the name and email")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	user.Name = name.String
+	user.Email = email.String
+	return passwordHash, nil
+}
+
+// GetOrigin returns the origin for this client
+func (c SQLClient) GetOrigin() string {
+	return c.Origin
+}
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testDriver is a minimal database/sql driver serving fixed results for the queries used by the tests
+type testDriver struct{}
+type testConn struct{}
+type testStmt struct{ query string }
+type testRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+var testUsers = map[string][]driver.Value{
+	"test":  {testBcryptHash, "My Name", "test@test.com"},
+	"test2": {testBcryptHash, nil, nil},
+}
+
+var testRoles = map[string][]string{
+	"test": {"testRole", "testRole2"},
+}
+
+func init() {
+	sql.Register("authtest", testDriver{})
+}
+
+func (d testDriver) Open(name string) (driver.Conn, error) { return testConn{}, nil }
+
+func (c testConn) Prepare(query string) (driver.Stmt, error) { return testStmt{query}, nil }
+func (c testConn) Close() error                              { return nil }
+func (c testConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+func (s testStmt) Close() error  { return nil }
+func (s testStmt) NumInput() int { return 1 }
+func (s testStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (s testStmt) Query(args []driver.Value) (driver.Rows, error) {
+	username := args[0].(string)
+	switch s.query {
+	case "users":
+		rows := &testRows{columns: []string{"hash", "name", "email"}}
+		if u, ok := testUsers[username]; ok {
+			rows.values = append(rows.values, u)
+		}
+		return rows, nil
+	case "roles":
+		rows := &testRows{columns: []string{"role"}}
+		for _, r := range testRoles[username] {
+			rows.values = append(rows.values, []driver.Value{r})
+		}
+		return rows, nil
+	}
+
+	return nil, errors.New("unknown query")
+}
+
+func (r *testRows) Columns() []string { return r.columns }
+func (r *testRows) Close() error      { return nil }
+func (r *testRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func TestNewSQLClient(t *testing.T) {
+	c, err := NewSQLClient(map[interface{}]interface{}{"driver": "authtest", "dsn": "memory", "origin": "testOrigin", "passwordQuery": "users", "rolesQuery": "roles"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlClient := c.(*SQLClient)
+	assert.Equal(t, "testOrigin", sqlClient.GetOrigin())
+	assert.Equal(t, "users", sqlClient.PasswordQuery)
+	assert.Equal(t, "roles", sqlClient.RolesQuery)
+
+	_, err = NewSQLClient(map[interface{}]interface{}{"driver": "authtest", "dsn": "memory"})
+	assert.EqualError(t, err, "passwordQuery must be specified in configuration")
+
+	_, err = NewSQLClient(map[interface{}]interface{}{"driver": "unknown", "dsn": "memory", "passwordQuery": "users"})
+	assert.Error(t, err)
+}
+
+func TestSQLValidateCredentials(t *testing.T) {
+	c, err := NewSQLClient(map[interface{}]interface{}{"driver": "authtest", "dsn": "memory", "origin": "testOrigin", "passwordQuery": "users", "rolesQuery": "roles"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := c.ValidateCredentials("test", "testpass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "testOrigin", u.Origin)
+	assert.Equal(t, "test", u.Username)
+	assert.Equal(t, "My Name", u.Name)
+	assert.Equal(t, "test@test.com", u.Email)
+	assert.Equal(t, []string{"testRole", "testRole2"}, u.Roles)
+
+	u, err = c.ValidateCredentials("test2", "testpass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "", u.Name)
+	assert.Equal(t, 0, len(u.Roles))
+
+	_, err = c.ValidateCredentials("test", "invalidpass")
+	assert.EqualError(t, err, "invalid credentials")
+
+	_, err = c.ValidateCredentials("unknown", "testpass")
+	assert.EqualError(t, err, "invalid credentials")
+	// Unknown users are checked against the hash last read so they take as long as a wrong password
+	assert.Equal(t, testBcryptHash, c.(*SQLClient).sampleHash())
+}
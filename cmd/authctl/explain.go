@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ticketmaster/authentication"
+	"github.com/ticketmaster/authentication/authorization"
+)
+
+func runExplain(args []string, s streams) error {
+	fs := newFlagSet("explain", s)
+	path := fs.String("config", "authentication.yaml", "configuration file with the authorization rules")
+	casesPath := fs.String("cases", "", "YAML file of cases to explain instead of the case given by flags")
+	c := authorization.PolicyCase{}
+	fs.StringVar(&c.User, "user", "", "username")
+	fs.StringVar(&c.FullName, "name", "", "name of the user")
+	fs.StringVar(&c.Email, "email", "", "email of the user")
+	fs.StringVar(&c.Origin, "origin", "", "origin of the user")
+	roles := fs.String("roles", "", "comma separated roles of the user")
+	claims := fs.String("claims", "", "token claims of the user as a JSON object")
+	fs.StringVar(&c.Method, "method", "", "request method, GET if a route is given")
+	fs.StringVar(&c.Route, "route", "", "request route, e.g. /api/users/1")
+	fs.StringVar(&c.Action, "action", "", "action, e.g. App.Index")
+	fs.StringVar(&c.Expect, "expect", "", "expected decision, allow or deny")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	config, err := authentication.LoadConfig(*path)
+	if err != nil {
+		return err
+	}
+	if problems := authorization.Validate(config.Authorization); len(problems) > 0 {
+		return fmt.Errorf("invalid authorization configuration:\nauthorization.%s", strings.Join(problems, "\nauthorization."))
+	}
+	auth, err := authorization.NewAuthorization(config.Authorization)
+	if err != nil {
+		return err
+	}
+
+	var cases []authorization.PolicyCase
+	if len(*casesPath) > 0 {
+		cases, err = authorization.LoadPolicyCases(*casesPath)
+		if err != nil {
+			return err
+		}
+	} else {
+		if len(c.Route) == 0 && len(c.Action) == 0 {
+			return errors.New("route or action must be specified")
+		}
+		c.Roles = splitList(*roles)
+		if len(*claims) > 0 {
+			err = json.Unmarshal([]byte(*claims), &c.Claims)
+			if err != nil {
+				return fmt.Errorf("invalid claims: %v", err)
+			}
+		}
+		cases = append(cases, c)
+	}
+
+	failed := 0
+	for idx, c := range cases {
+		if idx > 0 {
+			fmt.Fprintln(s.out)
+		}
+		explanation, err := auth.CheckPolicyCase(c)
+		fmt.Fprintf(s.out, "%s\n%s\n", c, explanation)
+		if err != nil {
+			failed++
+			fmt.Fprintf(s.out, "FAIL: %v\n", err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%v of %v cases did not get the expected decision", failed, len(cases))
+	}
+
+	return nil
+}
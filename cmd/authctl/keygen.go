@@ -0,0 +1,124 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/ticketmaster/authentication/common"
+)
+
+func runKeygen(args []string, s streams) error {
+	fs := newFlagSet("keygen", s)
+	algorithm := fs.String("algorithm", common.DefaultJwtAlgorithm, "jwtAlgorithm the keys are for: RS*, PS*, ES256/384/512 or EdDSA")
+	bits := fs.Int("bits", 2048, "size of RSA keys")
+	privatePath := fs.String("private", "private.key", "file to write the private key to")
+	publicPath := fs.String("public", "public.pem", "file to write the public key to")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	method, err := common.GetSigningMethod(*algorithm)
+	if err != nil {
+		return err
+	}
+
+	private, public, err := generateKey(method, *bits)
+	if err != nil {
+		return err
+	}
+
+	keyID, err := common.KeyThumbprint(public)
+	if err != nil {
+		return err
+	}
+
+	err = writePEM(*privatePath, 0600, private)
+	if err != nil {
+		return err
+	}
+	err = writePEM(*publicPath, 0644, public)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(s.out, "jwtAlgorithm: %s\nprivateKey: %s\npublicKey: %s\nkeyID: %s\n", *algorithm, *privatePath, *publicPath, keyID)
+	return nil
+}
+
+// generateKey creates a key pair of the type the signing method requires
+func generateKey(method jwt.SigningMethod, bits int) (crypto.PrivateKey, crypto.PublicKey, error) {
+	switch m := method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		key, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return nil, nil, err
+		}
+		return key, &key.PublicKey, nil
+	case *jwt.SigningMethodECDSA:
+		var curve elliptic.Curve
+		switch m.CurveBits {
+		case 256:
+			curve = elliptic.P256()
+		case 384:
+			curve = elliptic.P384()
+		default:
+			curve = elliptic.P521()
+		}
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		return key, &key.PublicKey, nil
+	case *common.SigningMethodEd25519:
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, public, err
+	}
+
+	return nil, nil, fmt.Errorf("unsupported jwt algorithm: %s", method.Alg())
+}
+
+// writePEM writes a key in the PEM encoding common.ReadPrivateKey and common.ReadPublicKey expect. Existing files are
+// not overwritten.
+func writePEM(path string, mode os.FileMode, key interface{}) error {
+	var block *pem.Block
+	var err error
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}
+	case *ecdsa.PrivateKey:
+		block = &pem.Block{Type: "EC PRIVATE KEY"}
+		block.Bytes, err = x509.MarshalECPrivateKey(k)
+	case ed25519.PrivateKey:
+		block = &pem.Block{Type: "PRIVATE KEY"}
+		block.Bytes, err = x509.MarshalPKCS8PrivateKey(k)
+	default:
+		block = &pem.Block{Type: "PUBLIC KEY"}
+		block.Bytes, err = x509.MarshalPKIXPublicKey(k)
+	}
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+
+	err = pem.Encode(f, block)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,102 @@
+// Command authctl lints authentication configuration and works with the keys, tokens and password hashes it uses.
+//
+// Usage:
+//
+//	authctl [-logtostderr] [-v level] <command> [flags]
+//
+// Paths in the configuration, such as privateKey, are relative to the working directory, so run authctl from the
+// directory the service runs in.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+type streams struct {
+	in  io.Reader
+	out io.Writer
+	err io.Writer
+}
+
+type command struct {
+	usage string
+	run   func(args []string, s streams) error
+}
+
+var commands = map[string]command{
+	"validate":      {"check a configuration file and report every problem", runValidate},
+	"keygen":        {"create a key pair for privateKey and publicKey", runKeygen},
+	"token":         {"issue or inspect a token (token issue, token inspect)", runToken},
+	"hash-password": {"hash a password read from stdin for a local user", runHashPassword},
+	"explain":       {"explain the authorization decision for a request or a file of cases", runExplain},
+}
+
+func main() {
+	flag.Usage = func() { usage(os.Stderr) }
+	flag.Parse()
+	os.Exit(run(flag.Args(), streams{os.Stdin, os.Stdout, os.Stderr}))
+}
+
+// run executes the command named by the first argument and returns the exit code
+func run(args []string, s streams) int {
+	if len(args) == 0 {
+		usage(s.err)
+		return 2
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(s.err, "authctl: unknown command %s\n", args[0])
+		usage(s.err)
+		return 2
+	}
+
+	err := cmd.run(args[1:], s)
+	if err == flag.ErrHelp {
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(s.err, "authctl %s: %v\n", args[0], err)
+		return 1
+	}
+
+	return 0
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: authctl <command> [flags]")
+	fmt.Fprintln(w, "\ncommands:")
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-14s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintln(w, "\nrun authctl <command> -h for the flags of a command")
+}
+
+// newFlagSet creates the flag set of a command, reporting parse errors to stderr
+func newFlagSet(name string, s streams) *flag.FlagSet {
+	fs := flag.NewFlagSet("authctl "+name, flag.ContinueOnError)
+	fs.SetOutput(s.err)
+	return fs
+}
+
+// splitList splits a comma separated flag value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
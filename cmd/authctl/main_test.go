@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ticketmaster/authentication/client"
+)
+
+// runCommand runs authctl with the arguments and returns the exit code, stdout and stderr
+func runCommand(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, streams{strings.NewReader(stdin), &stdout, &stderr})
+	return code, stdout.String(), stderr.String()
+}
+
+// assertExpiresIn checks that the remaining lifetime printed by token inspect is within the bounds
+func assertExpiresIn(t *testing.T, out string, min time.Duration, max time.Duration) {
+	match := regexp.MustCompile(`\(in ([^)]+)\)`).FindStringSubmatch(out)
+	if !assert.NotNil(t, match, out) {
+		return
+	}
+
+	remaining, err := time.ParseDuration(match[1])
+	if assert.Nil(t, err) {
+		assert.True(t, remaining >= min && remaining <= max, "expires in %s", remaining)
+	}
+}
+
+func TestTokenRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "authctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, algorithm := range []string{"RS256", "ES256", "EdDSA"} {
+		private := filepath.Join(dir, algorithm+".key")
+		public := filepath.Join(dir, algorithm+".pem")
+		code, out, errOut := runCommand("", "keygen", "-algorithm", algorithm, "-bits", "1024", "-private", private, "-public", public)
+		if !assert.Equal(t, 0, code, errOut) {
+			continue
+		}
+		assert.Contains(t, out, "jwtAlgorithm: "+algorithm)
+
+		// Existing keys are not overwritten
+		code, _, _ = runCommand("", "keygen", "-algorithm", algorithm, "-private", private, "-public", public)
+		assert.Equal(t, 1, code)
+
+		config := filepath.Join(dir, algorithm+".yaml")
+		err = ioutil.WriteFile(config, []byte(fmt.Sprintf("jwtAlgorithm: %s\nprivateKey: %s\npublicKey: %s\n", algorithm, private, public)), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// jwtExpiration is not configured
+		code, _, errOut = runCommand("", "token", "issue", "-config", config, "-username", "test")
+		assert.Equal(t, 1, code)
+		assert.Contains(t, errOut, "jwtExpiration: must be specified")
+
+		code, token, errOut := runCommand("", "token", "issue", "-config", config, "-username", "test", "-roles", "admin, auditor", "-expiration", "10m")
+		if !assert.Equal(t, 0, code, errOut) {
+			continue
+		}
+
+		err = ioutil.WriteFile(config, []byte(fmt.Sprintf("jwtAlgorithm: %s\npublicKey: %s\njwtExpiration: 1h\n", algorithm, public)), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code, out, errOut = runCommand(token, "token", "inspect", "-config", config)
+		assert.Equal(t, 0, code, errOut)
+		assert.Contains(t, out, `"alg": "`+algorithm+`"`)
+		assert.Contains(t, out, `"auditor"`)
+		assertExpiresIn(t, out, 9*time.Minute, 10*time.Minute)
+		assert.Contains(t, out, "token is valid")
+
+		code, _, errOut = runCommand("", "token", "inspect", "-config", config, token[:len(token)-4]+"AAAA")
+		assert.Equal(t, 1, code)
+		assert.Contains(t, errOut, "token is not valid")
+	}
+}
+
+func TestValidateCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "authctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "authentication.yaml")
+	write := func(config string) {
+		err := ioutil.WriteFile(path, []byte(config), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`
+authenticationClient:
+  - provider: memory
+    origin: local
+    users:
+      - username: test
+        passwordHash: "$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy"
+        roles: [user]
+authorization:
+  rules:
+    - ruleType: route
+      path: /admin/*
+      authorize: allow
+      role: admin
+      origin: local
+jwtExpiration: 1h
+`)
+	code, out, _ := runCommand("", "validate", "-config", path)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "warning: authorization.rules[0].role[0]: no authentication client grants role admin\n"+path+" is valid\n", out)
+
+	code, _, errOut := runCommand("", "validate", "-config", path, "-strict")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, "0 error(s), 1 warning(s)")
+
+	write(`
+authenticationClient:
+  - provider: ldapp
+jwtExpiration: 1h
+`)
+	code, out, _ = runCommand("", "validate", "-config", path)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "error: authenticationClient[0].provider: unknown provider: ldapp\n", out)
+}
+
+func TestHashPassword(t *testing.T) {
+	code, out, _ := runCommand("secret\n", "hash-password", "-algorithm", "bcrypt")
+	if assert.Equal(t, 0, code) {
+		ok, err := client.VerifyPassword(strings.TrimSpace(out), "secret")
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	}
+
+	code, _, errOut := runCommand("", "hash-password")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, "password must be given on stdin")
+
+	code, _, _ = runCommand("", "unknown")
+	assert.Equal(t, 2, code)
+}
+
+func TestExplainCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "authctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "authentication.yaml")
+	err = ioutil.WriteFile(path, []byte(`
+authorization:
+  default: deny
+  rules:
+    - ruleType: route
+      path: /api/*
+      method: GET
+      authorize: allow
+      role: reader
+      origin: corp
+    - ruleType: claim
+      authorize: allow
+      claims:
+        - claim: groups
+          value: admins
+    - ruleType: claim
+      authorize: allow
+      method: DELETE
+      claims:
+        - claim: email
+          operator: regex
+          value: "@example\\.com$"
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, out, errOut := runCommand("", "explain", "-config", path, "-user", "jdoe", "-origin", "corp", "-roles", "reader", "-method", "POST", "-route", "/api/users", "-claims", `{"groups": ["admins"]}`)
+	assert.Equal(t, 0, code, errOut)
+	assert.Equal(t, `corp/jdoe [reader] POST /api/users
+allow by claim rule 1 (match length 0); matched rules: 1 (deny-overrides)
+  rule 0 route allow: no match: method POST is not one of GET
+  rule 1 claim allow: match, deciding
+  rule 2 claim allow: no match: method POST is not one of DELETE
+`, out)
+
+	cases := filepath.Join(dir, "cases.yaml")
+	err = ioutil.WriteFile(cases, []byte(`
+- name: readers list users
+  origin: corp
+  roles: [reader]
+  route: /api/users
+  expect: allow
+- name: readers can not delete users
+  origin: corp
+  roles: [reader]
+  method: DELETE
+  route: /api/users/1
+  expect: allow
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, out, errOut = runCommand("", "explain", "-config", path, "-cases", cases)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out, "readers can not delete users\ndeny by default (deny-overrides)")
+	assert.Contains(t, out, "FAIL: expected allow")
+	assert.Contains(t, errOut, "1 of 2 cases did not get the expected decision")
+
+	code, out, errOut = runCommand("", "explain", "-config", path, "-user", "jdoe", "-name", "John Doe", "-email", "jdoe@example.com", "-method", "DELETE", "-route", "/api/users/1")
+	assert.Equal(t, 0, code, errOut)
+	assert.Contains(t, out, "allow by claim rule 2")
+
+	code, _, errOut = runCommand("", "explain", "-config", path, "-user", "jdoe")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, "route or action must be specified")
+}
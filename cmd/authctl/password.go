@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ticketmaster/authentication/client"
+)
+
+// runHashPassword reads the password from stdin rather than the arguments, which end up in the shell history
+func runHashPassword(args []string, s streams) error {
+	fs := newFlagSet("hash-password", s)
+	algorithm := fs.String("algorithm", client.DefaultPasswordHasher, "argon2id, bcrypt, scrypt, pbkdf2-sha256 or pbkdf2-sha512")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	line, _ := bufio.NewReader(s.in).ReadString('\n')
+	password := strings.TrimRight(line, "\r\n")
+	if len(password) == 0 {
+		return errors.New("password must be given on stdin")
+	}
+
+	hash, err := client.HashPassword(*algorithm, password)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(s.out, hash)
+	return nil
+}
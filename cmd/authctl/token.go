@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/ticketmaster/authentication"
+	"github.com/ticketmaster/authentication/common"
+)
+
+func runToken(args []string, s streams) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "issue":
+			return runTokenIssue(args[1:], s)
+		case "inspect":
+			return runTokenInspect(args[1:], s)
+		}
+	}
+
+	fmt.Fprintln(s.err, "usage: authctl token issue|inspect [flags]")
+	return flag.ErrHelp
+}
+
+func runTokenIssue(args []string, s streams) error {
+	fs := newFlagSet("token issue", s)
+	path := fs.String("config", "authentication.yaml", "configuration file with the signing key")
+	username := fs.String("username", "", "username of the token subject")
+	origin := fs.String("origin", "", "origin of the user")
+	roles := fs.String("roles", "", "comma separated roles of the user")
+	name := fs.String("name", "", "display name of the user")
+	email := fs.String("email", "", "email of the user")
+	expiration := fs.Duration("expiration", 0, "lifetime of the token, defaults to jwtExpiration")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(*username) == 0 {
+		return errors.New("username must be specified")
+	}
+
+	config, err := authentication.LoadConfig(*path)
+	if err != nil {
+		return err
+	}
+	if *expiration > 0 {
+		config.JwtExpiration = *expiration
+	}
+
+	manager, err := authentication.NewManagerFromConfig(config)
+	if err != nil {
+		return err
+	}
+
+	user := &common.User{Origin: *origin, Username: *username, Name: *name, Email: *email, Roles: splitList(*roles)}
+
+	token, err := manager.GetJwt(user)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(s.out, token)
+	return nil
+}
+
+func runTokenInspect(args []string, s streams) error {
+	fs := newFlagSet("token inspect", s)
+	path := fs.String("config", "authentication.yaml", "configuration file with the verification keys, empty to only decode the token")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	tokenString := fs.Arg(0)
+	if len(tokenString) == 0 || tokenString == "-" {
+		tokenString, err = bufio.NewReader(s.in).ReadString('\n')
+		if err != nil && len(tokenString) == 0 {
+			return errors.New("token must be given as an argument or on stdin")
+		}
+	}
+	tokenString = strings.TrimPrefix(strings.TrimSpace(tokenString), "Bearer ")
+
+	claims := jwt.MapClaims{}
+	token, _, err := new(jwt.Parser).ParseUnverified(tokenString, claims)
+	if err != nil {
+		return err
+	}
+
+	err = printJSON(s, "header", token.Header)
+	if err != nil {
+		return err
+	}
+	err = printJSON(s, "claims", claims)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if iat, ok := claims["iat"].(float64); ok {
+		fmt.Fprintf(s.out, "issued:  %s\n", time.Unix(int64(iat), 0).Format(time.RFC3339))
+	}
+	if exp, ok := claims["exp"].(float64); ok {
+		expires := time.Unix(int64(exp), 0)
+		if expires.After(now) {
+			fmt.Fprintf(s.out, "expires: %s (in %s)\n", expires.Format(time.RFC3339), expires.Sub(now).Round(time.Second))
+		} else {
+			fmt.Fprintf(s.out, "expires: %s (expired %s ago)\n", expires.Format(time.RFC3339), now.Sub(expires).Round(time.Second))
+		}
+	}
+
+	if len(*path) == 0 {
+		return nil
+	}
+
+	config, err := authentication.LoadConfig(*path)
+	if err != nil {
+		return err
+	}
+	manager, err := authentication.NewManagerFromConfig(config)
+	if err != nil {
+		return err
+	}
+
+	_, err = manager.CreateUserFromTokenString(tokenString)
+	if err != nil {
+		return fmt.Errorf("token is not valid: %v", err)
+	}
+
+	fmt.Fprintln(s.out, "token is valid")
+	return nil
+}
+
+func printJSON(s streams, name string, value interface{}) error {
+	b, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(s.out, "%s: %s\n", name, b)
+	return nil
+}
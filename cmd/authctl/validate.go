@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ticketmaster/authentication"
+)
+
+func runValidate(args []string, s streams) error {
+	fs := newFlagSet("validate", s)
+	path := fs.String("config", "authentication.yaml", "configuration file (yaml, json or toml)")
+	strict := fs.Bool("strict", false, "treat warnings as errors")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	config, err := authentication.LoadConfig(*path)
+	if err != nil {
+		return err
+	}
+
+	warnings, err := config.Validate()
+	for _, warning := range warnings {
+		fmt.Fprintf(s.out, "warning: %s\n", warning)
+	}
+
+	var problems []string
+	if validationErr, ok := err.(*authentication.ValidationError); ok {
+		problems = validationErr.Errors
+	} else if err != nil {
+		return err
+	}
+	for _, problem := range problems {
+		fmt.Fprintf(s.out, "error: %s\n", problem)
+	}
+
+	if len(problems) > 0 || (*strict && len(warnings) > 0) {
+		return fmt.Errorf("%s: %v error(s), %v warning(s)", *path, len(problems), len(warnings))
+	}
+
+	fmt.Fprintf(s.out, "%s is valid\n", *path)
+	return nil
+}
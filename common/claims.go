@@ -0,0 +1,98 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// ClaimMapping names the claims used to populate the fields of a User from an externally issued token
+type ClaimMapping struct {
+	Username string
+	Name     string
+	Email    string
+	Roles    string
+}
+
+// SetDefaults fills in the standard OpenID Connect claim names for any unset fields
+func (m *ClaimMapping) SetDefaults() {
+	if len(m.Username) == 0 {
+		m.Username = "preferred_username"
+	}
+	if len(m.Name) == 0 {
+		m.Name = "name"
+	}
+	if len(m.Email) == 0 {
+		m.Email = "email"
+	}
+	if len(m.Roles) == 0 {
+		m.Roles = "groups"
+	}
+}
+
+// CreateUser builds a User from the claims. If the username claim is not present the sub claim is used.
+func (m ClaimMapping) CreateUser(origin string, claims jwt.MapClaims) *User {
+	user := &User{
+		Origin:   origin,
+		Username: ClaimString(claims, m.Username),
+		Name:     ClaimString(claims, m.Name),
+		Email:    ClaimString(claims, m.Email),
+		Roles:    ClaimStrings(claims, m.Roles),
+	}
+	if len(user.Username) == 0 {
+		user.Username = ClaimString(claims, "sub")
+	}
+
+	return user
+}
+
+// ClaimString returns the claim as a string, or an empty string if it is not present
+func ClaimString(claims jwt.MapClaims, name string) string {
+	v, ok := claims[name]
+	if !ok || v == nil {
+		return ""
+	}
+
+	if s, ok := v.(string); ok {
+		return s
+	}
+
+	return fmt.Sprintf("%v", v)
+}
+
+// ClaimStrings returns a list claim as a slice of strings. A string claim is split on commas and whitespace.
+func ClaimStrings(claims jwt.MapClaims, name string) []string {
+	var values []string
+	switch v := claims[name].(type) {
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	case []string:
+		values = append(values, v...)
+	case string:
+		values = strings.FieldsFunc(v, func(r rune) bool {
+			return r == ',' || r == ' '
+		})
+	}
+
+	return values
+}
+
+// HasAudience returns true if the aud claim, either a string or a list, contains the audience
+func HasAudience(claims jwt.MapClaims, audience string) bool {
+	if aud, ok := claims["aud"].(string); ok {
+		return aud == audience
+	}
+
+	for _, aud := range ClaimStrings(claims, "aud") {
+		if aud == audience {
+			return true
+		}
+	}
+
+	return false
+}
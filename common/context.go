@@ -0,0 +1,18 @@
+package common
+
+import "context"
+
+type contextKey struct{}
+
+var userContextKey = contextKey{}
+
+// NewContext returns a copy of the parent context that carries the authenticated user
+func NewContext(parent context.Context, u *User) context.Context {
+	return context.WithValue(parent, userContextKey, u)
+}
+
+// FromContext returns the authenticated user stored in the context, if any
+func FromContext(ctx context.Context) (*User, bool) {
+	u, ok := ctx.Value(userContextKey).(*User)
+	return u, ok && u != nil
+}
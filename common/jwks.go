@@ -0,0 +1,319 @@
+package common
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// JSONWebKey is a single key of a JSON Web Key Set (RFC 7517)
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet is a JSON Web Key Set document
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// PublicKey returns the crypto public key described by the JSON Web Key
+func (k JSONWebKey) PublicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported EC curve: %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC key point is not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported OKP curve: %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key length")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type: %s", k.Kty)
+}
+
+// NewJSONWebKey describes a RSA, ECDSA or Ed25519 public key as a JSON Web Key
+func NewJSONWebKey(key crypto.PublicKey) (JSONWebKey, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return JSONWebKey{
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		return JSONWebKey{
+			Kty: "EC",
+			Crv: k.Curve.Params().Name,
+			X:   base64.RawURLEncoding.EncodeToString(padBytes(k.X.Bytes(), size)),
+			Y:   base64.RawURLEncoding.EncodeToString(padBytes(k.Y.Bytes(), size)),
+		}, nil
+	case ed25519.PublicKey:
+		return JSONWebKey{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(k),
+		}, nil
+	}
+
+	return JSONWebKey{}, fmt.Errorf("unsupported key type: %T", key)
+}
+
+func padBytes(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+
+	padded := make([]byte, size)
+	copy(padded[size-len(b):], b)
+	return padded
+}
+
+// Thumbprint returns the RFC 7638 thumbprint of the key
+func (k JSONWebKey) Thumbprint() string {
+	var members interface{}
+	switch k.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{k.E, k.Kty, k.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{k.Crv, k.Kty, k.X, k.Y}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{k.Crv, k.Kty, k.X}
+	}
+
+	b, _ := json.Marshal(members)
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
+
+// ParseJWKS parses a JSON Web Key Set document into public keys by key ID. Keys of unsupported types are skipped.
+func ParseJWKS(data []byte) (map[string]interface{}, error) {
+	var set JSONWebKeySet
+	err := json.Unmarshal(data, &set)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{})
+	for idx, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.PublicKey()
+		if err != nil {
+			glog.Warningf("skipping JSON web key at index %v: %v", idx, err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	return keys, nil
+}
+
+// KeySet holds the verification keys of a JSON Web Key Set. Remote key sets are fetched on first use and refreshed when
+// an unknown key ID is requested, at most once per MinRefreshInterval whether the fetch succeeds or not. Keys already
+// loaded stay available while a fetch is in progress.
+type KeySet struct {
+	URL                string
+	HTTPClient         *http.Client
+	MinRefreshInterval time.Duration
+
+	mutex       sync.RWMutex
+	keys        map[string]interface{}
+	lastRefresh time.Time
+	refreshErr  error
+	// fetchMutex lets a single fetch run at a time
+	fetchMutex sync.Mutex
+}
+
+// defaultJWKSClient fetches remote key sets unless a KeySet has its own HTTPClient
+var defaultJWKSClient = &http.Client{Timeout: 10 * time.Second}
+
+// NewRemoteKeySet creates a KeySet that fetches its keys from a JWKS URL
+func NewRemoteKeySet(url string) *KeySet {
+	return &KeySet{URL: url, HTTPClient: defaultJWKSClient, MinRefreshInterval: time.Minute}
+}
+
+// NewStaticKeySet creates a KeySet from a JWKS document
+func NewStaticKeySet(data []byte) (*KeySet, error) {
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeySet{keys: keys}, nil
+}
+
+// Key returns the verification key with the specified key ID. If kid is empty and the set holds a single key, that key is returned.
+func (s *KeySet) Key(kid string) (interface{}, error) {
+	key, ok := s.lookup(kid)
+	if ok {
+		return key, nil
+	}
+
+	if len(s.URL) == 0 {
+		return nil, fmt.Errorf("unknown key id: %s", kid)
+	}
+
+	err := s.refresh()
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok = s.lookup(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown key id: %s", kid)
+	}
+
+	return key, nil
+}
+
+func (s *KeySet) lookup(kid string) (interface{}, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// refresh fetches the keys unless a fetch was attempted within MinRefreshInterval, in which case its error is returned.
+// Requests waiting for a fetch in progress share its outcome.
+func (s *KeySet) refresh() error {
+	s.fetchMutex.Lock()
+	defer s.fetchMutex.Unlock()
+
+	s.mutex.RLock()
+	recent := !s.lastRefresh.IsZero() && time.Since(s.lastRefresh) < s.MinRefreshInterval
+	err := s.refreshErr
+	s.mutex.RUnlock()
+	if recent {
+		return err
+	}
+
+	keys, err := s.fetch()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.lastRefresh = time.Now()
+	s.refreshErr = err
+	if err != nil {
+		return err
+	}
+
+	glog.V(2).Infof("Loaded %v keys from %s", len(keys), s.URL)
+	s.keys = keys
+	return nil
+}
+
+// fetch reads the key set from its URL
+func (s *KeySet) fetch() (map[string]interface{}, error) {
+	client := s.HTTPClient
+	if client == nil {
+		client = defaultJWKSClient
+	}
+
+	resp, err := client.Get(s.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status fetching %s: %s", s.URL, resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseJWKS(data)
+}
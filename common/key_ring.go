@@ -0,0 +1,382 @@
+package common
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/golang/glog"
+)
+
+// DefaultJwtAlgorithm is the signing algorithm used when none is configured
+const DefaultJwtAlgorithm = "RS256"
+
+// KeyRingConfig names the key files a KeyRing is loaded from
+type KeyRingConfig struct {
+	Algorithm        string
+	PrivateKey       string
+	PublicKey        string
+	KeyID            string
+	VerificationKeys []VerificationKeyConfig
+}
+
+// VerificationKeyConfig names a still trusted public key, its key ID and algorithm. If Algorithm is empty the key ring's algorithm is used.
+type VerificationKeyConfig struct {
+	ID        string
+	Algorithm string
+	PublicKey string
+}
+
+// SigningKey is a private key with the algorithm and key ID used to sign tokens
+type SigningKey struct {
+	ID     string
+	Method jwt.SigningMethod
+	Key    crypto.PrivateKey
+}
+
+// VerificationKey is a public key with the algorithm and key ID of the tokens it verifies
+type VerificationKey struct {
+	ID     string
+	Method jwt.SigningMethod
+	Key    crypto.PublicKey
+}
+
+// KeyRing holds the current signing key and the keys trusted to verify tokens, each identified by the key ID placed in
+// the JWT kid header. Tokens without a kid are verified with the current public key. A token is only accepted if its
+// alg header matches the algorithm of the selected key.
+type KeyRing struct {
+	Config KeyRingConfig
+
+	mutex            sync.RWMutex
+	signingKey       *SigningKey
+	currentKey       *VerificationKey
+	verificationKeys map[string]*VerificationKey
+}
+
+// GetSigningMethod returns the signing method for a supported JWA algorithm name
+func GetSigningMethod(algorithm string) (jwt.SigningMethod, error) {
+	switch algorithm {
+	case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA":
+		return jwt.GetSigningMethod(algorithm), nil
+	}
+
+	return nil, fmt.Errorf("unsupported jwt algorithm: %s", algorithm)
+}
+
+// NewKeyRing creates a KeyRing with the specified signing key pair. If keyID is empty the key's thumbprint is used and
+// if publicKey is nil it is derived from the signing key.
+func NewKeyRing(algorithm string, keyID string, signingKey crypto.PrivateKey, publicKey crypto.PublicKey) (*KeyRing, error) {
+	method, err := GetSigningMethod(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &KeyRing{Config: KeyRingConfig{Algorithm: algorithm, KeyID: keyID}, verificationKeys: make(map[string]*VerificationKey)}
+	err = r.setCurrent(method, keyID, signingKey, publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *KeyRing) setCurrent(method jwt.SigningMethod, keyID string, signingKey crypto.PrivateKey, publicKey crypto.PublicKey) error {
+	if signingKey != nil {
+		err := checkKeyType(method, signingKey)
+		if err != nil {
+			return err
+		}
+		if publicKey == nil {
+			publicKey = signingKey.(crypto.Signer).Public()
+		}
+	}
+
+	if publicKey == nil {
+		return nil
+	}
+
+	err := checkKeyType(method, publicKey)
+	if err != nil {
+		return err
+	}
+
+	if len(keyID) == 0 {
+		keyID, err = KeyThumbprint(publicKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	if signingKey != nil {
+		r.signingKey = &SigningKey{ID: keyID, Method: method, Key: signingKey}
+	}
+	r.currentKey = &VerificationKey{ID: keyID, Method: method, Key: publicKey}
+	r.verificationKeys[keyID] = r.currentKey
+	return nil
+}
+
+// checkKeyType returns an error if the key cannot be used with the signing method
+func checkKeyType(method jwt.SigningMethod, key interface{}) error {
+	var ok bool
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		switch key.(type) {
+		case *rsa.PrivateKey, *rsa.PublicKey:
+			ok = true
+		}
+	case *jwt.SigningMethodECDSA:
+		curveBits := method.(*jwt.SigningMethodECDSA).CurveBits
+		switch k := key.(type) {
+		case *ecdsa.PrivateKey:
+			ok = k.Curve.Params().BitSize == curveBits
+		case *ecdsa.PublicKey:
+			ok = k.Curve.Params().BitSize == curveBits
+		}
+	case *SigningMethodEd25519:
+		switch key.(type) {
+		case ed25519.PrivateKey, ed25519.PublicKey:
+			ok = true
+		}
+	}
+
+	if !ok {
+		return fmt.Errorf("key of type %T can not be used with algorithm %s", key, method.Alg())
+	}
+
+	return nil
+}
+
+// LoadKeyRing reads the key files named in the configuration and creates a KeyRing
+func LoadKeyRing(config KeyRingConfig) (*KeyRing, error) {
+	r := &KeyRing{Config: config}
+	err := r.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Reload reads the key files named in the configuration again and atomically replaces the keys. If any file cannot be
+// read the current keys are kept.
+func (r *KeyRing) Reload() error {
+	algorithm := r.Config.Algorithm
+	if len(algorithm) == 0 {
+		algorithm = DefaultJwtAlgorithm
+	}
+	method, err := GetSigningMethod(algorithm)
+	if err != nil {
+		return err
+	}
+
+	loaded := &KeyRing{verificationKeys: make(map[string]*VerificationKey)}
+	var signingKey crypto.PrivateKey
+	var publicKey crypto.PublicKey
+	if len(r.Config.PrivateKey) > 0 {
+		signingKey, err = ReadPrivateKey(method, r.Config.PrivateKey)
+		if err != nil {
+			return err
+		}
+	}
+	if len(r.Config.PublicKey) > 0 {
+		publicKey, err = ReadPublicKey(method, r.Config.PublicKey)
+		if err != nil {
+			return err
+		}
+	}
+	err = loaded.setCurrent(method, r.Config.KeyID, signingKey, publicKey)
+	if err != nil {
+		return err
+	}
+
+	for idx, vk := range r.Config.VerificationKeys {
+		vkMethod := method
+		if len(vk.Algorithm) > 0 {
+			vkMethod, err = GetSigningMethod(vk.Algorithm)
+			if err != nil {
+				return fmt.Errorf("verification key at index %v: %v", idx, err)
+			}
+		}
+		key, err := ReadPublicKey(vkMethod, vk.PublicKey)
+		if err != nil {
+			return fmt.Errorf("verification key at index %v: %v", idx, err)
+		}
+		id := vk.ID
+		if len(id) == 0 {
+			id, err = KeyThumbprint(key)
+			if err != nil {
+				return fmt.Errorf("verification key at index %v: %v", idx, err)
+			}
+		}
+		loaded.verificationKeys[id] = &VerificationKey{ID: id, Method: vkMethod, Key: key}
+	}
+
+	r.mutex.Lock()
+	r.signingKey = loaded.signingKey
+	r.currentKey = loaded.currentKey
+	r.verificationKeys = loaded.verificationKeys
+	r.mutex.Unlock()
+	glog.V(2).Infof("Loaded %s key ring with %v verification keys", algorithm, len(loaded.verificationKeys))
+	return nil
+}
+
+// ReadPrivateKey reads a PEM encoded private key of the type required by the signing method
+func ReadPrivateKey(method jwt.SigningMethod, path string) (crypto.PrivateKey, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var key crypto.PrivateKey
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		key, err = jwt.ParseRSAPrivateKeyFromPEM(b)
+	case *jwt.SigningMethodECDSA:
+		key, err = jwt.ParseECPrivateKeyFromPEM(b)
+	case *SigningMethodEd25519:
+		key, err = parsePKCS8PrivateKeyFromPEM(b)
+	default:
+		err = fmt.Errorf("unsupported jwt algorithm: %s", method.Alg())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return key, checkKeyType(method, key)
+}
+
+// ReadPublicKey reads a PEM encoded public key of the type required by the signing method
+func ReadPublicKey(method jwt.SigningMethod, path string) (crypto.PublicKey, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var key crypto.PublicKey
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		key, err = jwt.ParseRSAPublicKeyFromPEM(b)
+	case *jwt.SigningMethodECDSA:
+		key, err = jwt.ParseECPublicKeyFromPEM(b)
+	case *SigningMethodEd25519:
+		key, err = parsePKIXPublicKeyFromPEM(b)
+	default:
+		err = fmt.Errorf("unsupported jwt algorithm: %s", method.Alg())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return key, checkKeyType(method, key)
+}
+
+func parsePKCS8PrivateKeyFromPEM(b []byte) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("key must be PEM encoded")
+	}
+
+	return x509.ParsePKCS8PrivateKey(block.Bytes)
+}
+
+func parsePKIXPublicKeyFromPEM(b []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("key must be PEM encoded")
+	}
+
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// AddVerificationKey trusts an additional public key for verification of tokens signed with the algorithm
+func (r *KeyRing) AddVerificationKey(keyID string, algorithm string, key crypto.PublicKey) error {
+	method, err := GetSigningMethod(algorithm)
+	if err != nil {
+		return err
+	}
+
+	err = checkKeyType(method, key)
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.verificationKeys[keyID] = &VerificationKey{ID: keyID, Method: method, Key: key}
+	return nil
+}
+
+// SigningKey returns the current signing key
+func (r *KeyRing) SigningKey() (*SigningKey, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if r.signingKey == nil {
+		return nil, errors.New("no signing key configured")
+	}
+
+	return r.signingKey, nil
+}
+
+// VerificationKey returns the public key for the key ID. An empty key ID selects the current public key.
+func (r *KeyRing) VerificationKey(keyID string) (*VerificationKey, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if len(keyID) == 0 {
+		if r.currentKey == nil {
+			return nil, errors.New("no public key configured")
+		}
+		return r.currentKey, nil
+	}
+
+	key, ok := r.verificationKeys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id: %s", keyID)
+	}
+
+	return key, nil
+}
+
+// VerificationKeys returns the trusted public keys by key ID
+func (r *KeyRing) VerificationKeys() map[string]*VerificationKey {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	keys := make(map[string]*VerificationKey)
+	for id, key := range r.verificationKeys {
+		keys[id] = key
+	}
+
+	return keys
+}
+
+// keyFunc selects the verification key by the token's kid header and strictly checks the token's alg against it
+func (r *KeyRing) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, err := r.VerificationKey(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.Key, nil
+}
+
+// KeyThumbprint returns the RFC 7638 JWK thumbprint of a public key, used as its default key ID
+func KeyThumbprint(key crypto.PublicKey) (string, error) {
+	jwk, err := NewJSONWebKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	return jwk.Thumbprint(), nil
+}
@@ -0,0 +1,211 @@
+package common
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeKeyPair(t *testing.T, dir string, name string, key *rsa.PrivateKey) (string, string) {
+	privPath := filepath.Join(dir, name+".rsa")
+	pubPath := filepath.Join(dir, name+".rsa.pub")
+	pubBytes, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubBytes}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return privPath, pubPath
+}
+
+func TestKeyRingRotation(t *testing.T) {
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ring, err := NewKeyRing("RS256", "old", privateKey, publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := user.GetJwtWithKeyRing(ring, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _, _ := new(jwt.Parser).ParseUnverified(oldToken, jwt.MapClaims{})
+	assert.Equal(t, "old", parsed.Header["kid"])
+
+	// Rotate to a new signing key while still trusting the old one
+	rotated, err := NewKeyRing("RS256", "new", newKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, rotated.AddVerificationKey("old", "RS256", publicKey))
+	newToken, err := user.GetJwtWithKeyRing(rotated, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tokenString := range []string{oldToken, newToken} {
+		u, err := CreateUserFromTokenStringWithKeyRing(tokenString, rotated)
+		if assert.NoError(t, err) {
+			assert.Equal(t, user.Username, u.Username)
+		}
+	}
+
+	// Tokens signed with a key that is no longer trusted are rejected
+	untrusted, _ := NewKeyRing("RS256", "new", newKey, nil)
+	_, err = CreateUserFromTokenStringWithKeyRing(oldToken, untrusted)
+	assert.Error(t, err)
+
+	// Tokens without a kid are verified with the current public key
+	legacyToken, err := user.GetJwt(privateKey, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = CreateUserFromTokenStringWithKeyRing(legacyToken, ring)
+	assert.NoError(t, err)
+	_, err = CreateUserFromTokenStringWithKeyRing(legacyToken, rotated)
+	assert.Error(t, err)
+
+	// The default key ID is the key thumbprint
+	thumbprint, _ := KeyThumbprint(publicKey)
+	defaultRing, _ := NewKeyRing("RS256", "", privateKey, nil)
+	signingKey, err := defaultRing.SigningKey()
+	assert.NoError(t, err)
+	assert.Equal(t, thumbprint, signingKey.ID)
+}
+
+func TestKeyRingReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "key-ring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privPath, pubPath := writeKeyPair(t, dir, "current", privateKey)
+	ring, err := LoadKeyRing(KeyRingConfig{PrivateKey: privPath, PublicKey: pubPath, KeyID: "2019-01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := user.GetJwtWithKeyRing(ring, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Move the old key to a verification key and install the new key as current
+	oldPrivPath, oldPubPath := writeKeyPair(t, dir, "previous", privateKey)
+	writeKeyPair(t, dir, "current", newKey)
+	ring.Config.KeyID = "2019-02"
+	ring.Config.VerificationKeys = []VerificationKeyConfig{{ID: "2019-01", PublicKey: oldPubPath}}
+	err = ring.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signingKey, err := ring.SigningKey()
+	assert.NoError(t, err)
+	assert.Equal(t, "2019-02", signingKey.ID)
+	assert.Equal(t, newKey, signingKey.Key)
+	_, err = CreateUserFromTokenStringWithKeyRing(oldToken, ring)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(ring.VerificationKeys()))
+
+	// A missing file keeps the current keys
+	ring.Config.VerificationKeys = []VerificationKeyConfig{{ID: "2019-01", PublicKey: oldPrivPath + ".missing"}}
+	assert.Error(t, ring.Reload())
+	_, err = CreateUserFromTokenStringWithKeyRing(oldToken, ring)
+	assert.NoError(t, err)
+}
+
+func TestKeyRingAlgorithms(t *testing.T) {
+	dir, err := ioutil.TempDir("", "key-ring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecPriv, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPriv, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecPrivPath := filepath.Join(dir, "ec.pem")
+	edPrivPath := filepath.Join(dir, "ed.pem")
+	ioutil.WriteFile(ecPrivPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecPriv}), 0600)
+	ioutil.WriteFile(edPrivPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: edPriv}), 0600)
+
+	for algorithm, path := range map[string]string{"ES256": ecPrivPath, "EdDSA": edPrivPath} {
+		ring, err := LoadKeyRing(KeyRingConfig{Algorithm: algorithm, PrivateKey: path})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tokenString, err := user.GetJwtWithKeyRing(ring, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, _, _ := new(jwt.Parser).ParseUnverified(tokenString, jwt.MapClaims{})
+		assert.Equal(t, algorithm, parsed.Header["alg"])
+
+		u, err := CreateUserFromTokenStringWithKeyRing(tokenString, ring)
+		if assert.NoError(t, err, algorithm) {
+			assert.Equal(t, user.Username, u.Username)
+		}
+	}
+
+	// The token's alg must match the algorithm of the selected key
+	ring, _ := NewKeyRing("RS256", "rsa", privateKey, nil)
+	assert.NoError(t, ring.AddVerificationKey("ec", "ES256", &ecKey.PublicKey))
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{"username": "test"})
+	token.Header["kid"] = "rsa"
+	tokenString, _ := token.SignedString(ecKey)
+	_, err = CreateUserFromTokenStringWithKeyRing(tokenString, ring)
+	assert.Error(t, err)
+	token.Header["kid"] = "ec"
+	tokenString, _ = token.SignedString(ecKey)
+	_, err = CreateUserFromTokenStringWithKeyRing(tokenString, ring)
+	assert.NoError(t, err)
+
+	// Keys must match the configured algorithm
+	_, err = LoadKeyRing(KeyRingConfig{Algorithm: "ES384", PrivateKey: ecPrivPath})
+	assert.Error(t, err)
+	_, err = NewKeyRing("HS256", "", privateKey, nil)
+	assert.EqualError(t, err, "unsupported jwt algorithm: HS256")
+}
@@ -0,0 +1,133 @@
+package common
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrRefreshTokenNotFound is returned when a refresh token is unknown or has expired
+var ErrRefreshTokenNotFound = errors.New("refresh token not found")
+
+// ErrRefreshTokenReused is returned when an already exchanged refresh token is presented again. The whole token family is
+// revoked when this happens since either the client or an attacker holds a stolen token.
+var ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+
+// RefreshToken is the stored state of an opaque refresh token. Tokens issued by rotating a refresh token share the Family
+// of the token issued at login.
+type RefreshToken struct {
+	ID        string
+	Family    string
+	Origin    string
+	Username  string
+	Name      string
+	Email     string
+	Roles     []string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	Used      bool
+}
+
+// User returns the user the refresh token was issued to
+func (t RefreshToken) User() *User {
+	return &User{Origin: t.Origin, Username: t.Username, Name: t.Name, Email: t.Email, Roles: t.Roles}
+}
+
+// RefreshTokenStore persists refresh tokens by ID. Stores only ever see the hashed token ID, never the token itself.
+type RefreshTokenStore interface {
+	Save(token *RefreshToken) error
+	// Consume marks the token as used and returns its state from before the call
+	Consume(id string) (*RefreshToken, error)
+	RevokeFamily(family string) error
+}
+
+// RefreshTokenStoreConstructor is a function definition for refresh token store constructors
+type RefreshTokenStoreConstructor func(map[interface{}]interface{}) (RefreshTokenStore, error)
+
+// SupportedRefreshTokenStores holds the registered refresh token stores by provider name
+var SupportedRefreshTokenStores map[string]RefreshTokenStoreConstructor
+
+func init() {
+	SupportedRefreshTokenStores = make(map[string]RefreshTokenStoreConstructor)
+	RegisterSupportedRefreshTokenStore("memory", NewMemoryRefreshTokenStore)
+}
+
+// RegisterSupportedRefreshTokenStore registers a refresh token store for use
+func RegisterSupportedRefreshTokenStore(providerName string, constructor RefreshTokenStoreConstructor) {
+	SupportedRefreshTokenStores[providerName] = constructor
+}
+
+// NewRefreshTokenString creates a random opaque refresh token and returns it together with the ID it is stored under
+func NewRefreshTokenString() (string, string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, RefreshTokenID(token), nil
+}
+
+// RefreshTokenID returns the ID a refresh token is stored under
+func RefreshTokenID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// MemoryRefreshTokenStore keeps refresh tokens in memory. Tokens are lost on restart and not shared between instances.
+type MemoryRefreshTokenStore struct {
+	mutex  sync.Mutex
+	tokens map[string]*RefreshToken
+}
+
+// NewMemoryRefreshTokenStore creates a new in-memory refresh token store
+func NewMemoryRefreshTokenStore(config map[interface{}]interface{}) (RefreshTokenStore, error) {
+	return &MemoryRefreshTokenStore{tokens: make(map[string]*RefreshToken)}, nil
+}
+
+// Save stores the refresh token and drops expired tokens
+func (s *MemoryRefreshTokenStore) Save(token *RefreshToken) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now()
+	for id, t := range s.tokens {
+		if now.After(t.ExpiresAt) {
+			delete(s.tokens, id)
+		}
+	}
+
+	stored := *token
+	s.tokens[token.ID] = &stored
+	return nil
+}
+
+// Consume marks the token as used and returns its state from before the call
+func (s *MemoryRefreshTokenStore) Consume(id string) (*RefreshToken, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	t, ok := s.tokens[id]
+	if !ok || time.Now().After(t.ExpiresAt) {
+		return nil, ErrRefreshTokenNotFound
+	}
+
+	previous := *t
+	t.Used = true
+	return &previous, nil
+}
+
+// RevokeFamily removes all tokens of the family
+func (s *MemoryRefreshTokenStore) RevokeFamily(family string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for id, t := range s.tokens {
+		if t.Family == family {
+			delete(s.tokens, id)
+		}
+	}
+
+	return nil
+}
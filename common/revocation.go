@@ -0,0 +1,196 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RevocationStore records revoked tokens by their jti claim and users whose tokens issued up to a point in time are
+// revoked. Entries are only needed until the tokens they cover expire.
+type RevocationStore interface {
+	RevokeToken(id string, expiresAt time.Time) error
+	RevokeUser(user string, issuedBefore time.Time, expiresAt time.Time) error
+	IsRevoked(id string, user string, issuedAt time.Time) (bool, error)
+}
+
+// RevocationStoreConstructor is a function definition for revocation store constructors
+type RevocationStoreConstructor func(map[interface{}]interface{}) (RevocationStore, error)
+
+// SupportedRevocationStores holds the registered revocation stores by provider name
+var SupportedRevocationStores map[string]RevocationStoreConstructor
+
+func init() {
+	SupportedRevocationStores = make(map[string]RevocationStoreConstructor)
+	RegisterSupportedRevocationStore("memory", NewMemoryRevocationStore)
+	RegisterSupportedRevocationStore("file", NewFileRevocationStore)
+}
+
+// RegisterSupportedRevocationStore registers a revocation store for use
+func RegisterSupportedRevocationStore(providerName string, constructor RevocationStoreConstructor) {
+	SupportedRevocationStores[providerName] = constructor
+}
+
+type revokedUser struct {
+	IssuedBefore time.Time
+	ExpiresAt    time.Time
+}
+
+type revocationList struct {
+	Tokens map[string]time.Time
+	Users  map[string]revokedUser
+}
+
+func newRevocationList() revocationList {
+	return revocationList{Tokens: make(map[string]time.Time), Users: make(map[string]revokedUser)}
+}
+
+func (l revocationList) evict(now time.Time) {
+	for id, expiresAt := range l.Tokens {
+		if now.After(expiresAt) {
+			delete(l.Tokens, id)
+		}
+	}
+	for user, revoked := range l.Users {
+		if now.After(revoked.ExpiresAt) {
+			delete(l.Users, user)
+		}
+	}
+}
+
+func (l revocationList) isRevoked(id string, user string, issuedAt time.Time) bool {
+	if _, ok := l.Tokens[id]; ok && len(id) > 0 {
+		return true
+	}
+
+	// Refresh tokens carry their full issue time. A JWT iat only has seconds, so tokens issued in the second of the
+	// revocation are revoked as well.
+	revoked, ok := l.Users[user]
+	return ok && !issuedAt.After(revoked.IssuedBefore)
+}
+
+// MemoryRevocationStore keeps revocations in memory and evicts them once the tokens they cover have expired
+type MemoryRevocationStore struct {
+	mutex sync.RWMutex
+	list  revocationList
+}
+
+// NewMemoryRevocationStore creates a new in-memory revocation store
+func NewMemoryRevocationStore(config map[interface{}]interface{}) (RevocationStore, error) {
+	return &MemoryRevocationStore{list: newRevocationList()}, nil
+}
+
+// RevokeToken revokes the token with the jti until it expires
+func (s *MemoryRevocationStore) RevokeToken(id string, expiresAt time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.list.evict(time.Now())
+	s.list.Tokens[id] = expiresAt
+	return nil
+}
+
+// RevokeUser revokes all tokens of the user issued up to issuedBefore
+func (s *MemoryRevocationStore) RevokeUser(user string, issuedBefore time.Time, expiresAt time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.list.evict(time.Now())
+	s.list.Users[user] = revokedUser{issuedBefore, expiresAt}
+	return nil
+}
+
+// IsRevoked returns true if the token or all of the user's tokens issued at its time have been revoked
+func (s *MemoryRevocationStore) IsRevoked(id string, user string, issuedAt time.Time) (bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.list.isRevoked(id, user, issuedAt), nil
+}
+
+// FileRevocationStore persists revocations to a JSON file so they survive restarts. The file is rewritten on each
+// revocation, so it is meant for a single instance with a modest logout rate.
+type FileRevocationStore struct {
+	Path string
+
+	mutex sync.RWMutex
+	list  revocationList
+}
+
+// NewFileRevocationStore creates a revocation store persisted at the configured path
+func NewFileRevocationStore(config map[interface{}]interface{}) (RevocationStore, error) {
+	path, _ := config["path"].(string)
+	if len(path) == 0 {
+		return nil, errors.New("path must be specified in configuration")
+	}
+
+	s := &FileRevocationStore{Path: path, list: newRevocationList()}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &s.list)
+	if err != nil {
+		return nil, err
+	}
+	if s.list.Tokens == nil {
+		s.list.Tokens = make(map[string]time.Time)
+	}
+	if s.list.Users == nil {
+		s.list.Users = make(map[string]revokedUser)
+	}
+
+	return s, nil
+}
+
+// RevokeToken revokes the token with the jti until it expires
+func (s *FileRevocationStore) RevokeToken(id string, expiresAt time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.list.Tokens[id] = expiresAt
+	return s.save()
+}
+
+// RevokeUser revokes all tokens of the user issued up to issuedBefore
+func (s *FileRevocationStore) RevokeUser(user string, issuedBefore time.Time, expiresAt time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.list.Users[user] = revokedUser{issuedBefore, expiresAt}
+	return s.save()
+}
+
+// IsRevoked returns true if the token or all of the user's tokens issued at its time have been revoked
+func (s *FileRevocationStore) IsRevoked(id string, user string, issuedAt time.Time) (bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.list.isRevoked(id, user, issuedAt), nil
+}
+
+// save evicts expired entries and atomically replaces the file
+func (s *FileRevocationStore) save() error {
+	s.list.evict(time.Now())
+	data, err := json.Marshal(s.list)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.Path), filepath.Base(s.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.Path)
+}
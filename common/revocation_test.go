@@ -0,0 +1,67 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryRevocationStore(t *testing.T) {
+	store, _ := NewMemoryRevocationStore(nil)
+	now := time.Now()
+
+	assert.NoError(t, store.RevokeToken("jti1", now.Add(time.Hour)))
+	revoked, _ := store.IsRevoked("jti1", "memory/test", now)
+	assert.True(t, revoked)
+	revoked, _ = store.IsRevoked("jti2", "memory/test", now)
+	assert.False(t, revoked)
+
+	// Tokens issued after a user revocation stay valid
+	assert.NoError(t, store.RevokeUser("memory/test", now, now.Add(time.Hour)))
+	revoked, _ = store.IsRevoked("jti2", "memory/test", now.Add(-time.Minute))
+	assert.True(t, revoked)
+	revoked, _ = store.IsRevoked("jti2", "memory/test", now.Add(time.Minute))
+	assert.False(t, revoked)
+	revoked, _ = store.IsRevoked("jti2", "memory/test", now.Truncate(time.Second))
+	assert.True(t, revoked)
+
+	// Expired entries are evicted
+	assert.NoError(t, store.RevokeToken("jti3", now.Add(-time.Second)))
+	assert.NoError(t, store.RevokeToken("jti4", now.Add(time.Hour)))
+	_, ok := store.(*MemoryRevocationStore).list.Tokens["jti3"]
+	assert.False(t, ok)
+}
+
+func TestFileRevocationStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "revocation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := map[interface{}]interface{}{"path": filepath.Join(dir, "revoked.json")}
+	store, err := NewFileRevocationStore(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	assert.NoError(t, store.RevokeToken("jti1", now.Add(time.Hour)))
+	assert.NoError(t, store.RevokeUser("memory/test", now, now.Add(time.Hour)))
+
+	// Revocations survive a restart
+	reopened, err := NewFileRevocationStore(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, _ := reopened.IsRevoked("jti1", "memory/other", now.Add(time.Minute))
+	assert.True(t, revoked)
+	revoked, _ = reopened.IsRevoked("", "memory/test", now.Add(-time.Minute))
+	assert.True(t, revoked)
+
+	_, err = NewFileRevocationStore(map[interface{}]interface{}{})
+	assert.EqualError(t, err, "path must be specified in configuration")
+}
@@ -0,0 +1,108 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/mitchellh/mapstructure"
+)
+
+// TrustedIssuer is an external token issuer whose JWTs are accepted after validation against its JSON Web Key Set
+type TrustedIssuer struct {
+	Issuer   string
+	Audience string
+	Origin   string
+	JwksURL  string
+	JwksFile string
+	Claims   ClaimMapping
+	Keys     *KeySet `mapstructure:"-"`
+}
+
+// NewTrustedIssuer creates a new TrustedIssuer from the specified configuration
+func NewTrustedIssuer(config map[interface{}]interface{}) (*TrustedIssuer, error) {
+	i := &TrustedIssuer{}
+	err := mapstructure.Decode(config, i)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []string
+	if len(i.Issuer) == 0 {
+		errs = append(errs, "issuer must be specified in configuration")
+	}
+	if len(i.Audience) == 0 {
+		errs = append(errs, "audience must be specified in configuration")
+	}
+	if len(i.JwksURL) == 0 && len(i.JwksFile) == 0 {
+		errs = append(errs, "jwksURL or jwksFile must be specified in configuration")
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("errors occurred creating trusted issuer: %v", strings.Join(errs, "\n"))
+	}
+
+	if len(i.Origin) == 0 {
+		i.Origin = i.Issuer
+	}
+	i.Claims.SetDefaults()
+
+	if len(i.JwksFile) > 0 {
+		data, err := ioutil.ReadFile(i.JwksFile)
+		if err != nil {
+			return nil, err
+		}
+		i.Keys, err = NewStaticKeySet(data)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		i.Keys = NewRemoteKeySet(i.JwksURL)
+	}
+
+	return i, nil
+}
+
+// CreateUserFromTokenString validates a token issued by this issuer and returns a User built from its claims
+func (i *TrustedIssuer) CreateUserFromTokenString(tokenString string) (*User, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS, *jwt.SigningMethodECDSA, *SigningMethodEd25519:
+		default:
+			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return i.Keys.Key(kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	if !claims.VerifyIssuer(i.Issuer, true) {
+		return nil, errors.New("token issuer does not match")
+	}
+
+	if !HasAudience(claims, i.Audience) {
+		return nil, errors.New("token audience does not match")
+	}
+
+	if _, ok := claims["exp"]; !ok {
+		return nil, errors.New("token does not have an expiration")
+	}
+
+	user := i.Claims.CreateUser(i.Origin, claims)
+	user.Token = token
+	return user, nil
+}
+
+// TokenIssuer returns the unverified iss claim of a token string
+func TokenIssuer(tokenString string) string {
+	token, _, err := new(jwt.Parser).ParseUnverified(tokenString, jwt.MapClaims{})
+	if err != nil {
+		return ""
+	}
+
+	return ClaimString(token.Claims.(jwt.MapClaims), "iss")
+}
@@ -0,0 +1,154 @@
+package common
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func testJWKS(kids ...string) []byte {
+	var keys []map[string]string
+	for _, kid := range kids {
+		keys = append(keys, map[string]string{
+			"kty": "RSA",
+			"kid": kid,
+			"n":   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+		})
+	}
+	data, _ := json.Marshal(map[string]interface{}{"keys": keys})
+	return data
+}
+
+func externalToken(kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	tokenString, _ := token.SignedString(privateKey)
+	return tokenString
+}
+
+func TestTrustedIssuer(t *testing.T) {
+	kids := []string{"key1"}
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Write(testJWKS(kids...))
+	}))
+	defer server.Close()
+
+	issuer, err := NewTrustedIssuer(map[interface{}]interface{}{
+		"issuer":   "https://idp.example.com",
+		"audience": "my-api",
+		"jwksURL":  server.URL,
+		"origin":   "gateway",
+		"claims":   map[interface{}]interface{}{"username": "sub", "roles": "scope"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer.Keys.MinRefreshInterval = 0
+
+	claims := jwt.MapClaims{"iss": "https://idp.example.com", "aud": "my-api", "sub": "svc-orders", "scope": "orders:read orders:write", "exp": time.Now().Add(time.Hour).Unix()}
+	tokenString := externalToken("key1", claims)
+	assert.Equal(t, "https://idp.example.com", TokenIssuer(tokenString))
+
+	u, err := issuer.CreateUserFromTokenString(tokenString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "gateway", u.Origin)
+	assert.Equal(t, "svc-orders", u.Username)
+	assert.Equal(t, []string{"orders:read", "orders:write"}, u.Roles)
+
+	// Keys are cached until an unknown kid is seen
+	_, err = issuer.CreateUserFromTokenString(tokenString)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fetches)
+
+	kids = append(kids, "key2")
+	_, err = issuer.CreateUserFromTokenString(externalToken("key2", claims))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fetches)
+
+	_, err = issuer.CreateUserFromTokenString(externalToken("key3", claims))
+	assert.Error(t, err)
+
+	claims["aud"] = "other-api"
+	_, err = issuer.CreateUserFromTokenString(externalToken("key1", claims))
+	assert.Error(t, err)
+
+	claims["aud"] = []string{"other-api", "my-api"}
+	delete(claims, "exp")
+	_, err = issuer.CreateUserFromTokenString(externalToken("key1", claims))
+	assert.Error(t, err)
+}
+
+func TestStaticKeySet(t *testing.T) {
+	keys, err := NewStaticKeySet(testJWKS("key1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := keys.Key("key1")
+	assert.NoError(t, err)
+	assert.Equal(t, publicKey, key)
+
+	// A single key is used for tokens without a kid
+	key, err = keys.Key("")
+	assert.NoError(t, err)
+	assert.Equal(t, publicKey, key)
+
+	_, err = keys.Key("key2")
+	assert.EqualError(t, err, "unknown key id: key2")
+}
+
+func TestRemoteKeySetOutage(t *testing.T) {
+	fetches := 0
+	fetching := make(chan bool)
+	release := make(chan bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		switch fetches {
+		case 1:
+			w.Write(testJWKS("key1"))
+		case 2:
+			fetching <- true
+			<-release
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	keys := NewRemoteKeySet(server.URL)
+	keys.MinRefreshInterval = 0
+	_, err := keys.Key("key1")
+	assert.NoError(t, err)
+
+	// Known keys are served while a fetch hangs
+	done := make(chan error)
+	go func() {
+		_, err := keys.Key("key2")
+		done <- err
+	}()
+	<-fetching
+	key, err := keys.Key("key1")
+	assert.NoError(t, err)
+	assert.Equal(t, publicKey, key)
+	close(release)
+	assert.Error(t, <-done)
+
+	// A failed fetch is not retried within MinRefreshInterval
+	keys.MinRefreshInterval = time.Minute
+	_, err = keys.Key("key2")
+	assert.EqualError(t, err, "unexpected status fetching "+server.URL+": 503 Service Unavailable")
+	assert.Equal(t, 2, fetches)
+}
@@ -1,7 +1,9 @@
 package common
 
 import (
+	"crypto/rand"
 	"crypto/rsa"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"time"
@@ -56,16 +58,60 @@ func CreateUserFromTokenString(tokenString string, verifyKey *rsa.PublicKey) (*U
 	return CreateUserFromToken(token)
 }
 
+// CreateUserFromTokenStringWithKeyRing parses a Jwt token string, verifying it with the key ring key selected by the kid header, and returns a User struct
+func CreateUserFromTokenStringWithKeyRing(tokenString string, ring *KeyRing) (*User, error) {
+	token, err := jwt.Parse(tokenString, ring.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	return CreateUserFromToken(token)
+}
+
 // GetJwt creates a sign Jwt
 func (u *User) GetJwt(signingKey *rsa.PrivateKey, expiration time.Duration) (string, error) {
+	return u.signJwt(&SigningKey{Method: jwt.SigningMethodRS256, Key: signingKey}, expiration, "", "")
+}
+
+// GetJwtWithKeyRing creates a Jwt signed with the key ring's current signing key and its key ID in the kid header
+func (u *User) GetJwtWithKeyRing(ring *KeyRing, expiration time.Duration) (string, error) {
+	signingKey, err := ring.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	return u.signJwt(signingKey, expiration, "", "")
+}
+
+// IssueJwt creates a Jwt signed with the signing key. The iss and aud claims are set to issuer and audience unless
+// they are empty.
+func (u *User) IssueJwt(signingKey *SigningKey, expiration time.Duration, issuer string, audience string) (string, error) {
+	return u.signJwt(signingKey, expiration, issuer, audience)
+}
+
+func (u *User) signJwt(signingKey *SigningKey, expiration time.Duration, issuer string, audience string) (string, error) {
 	if u == nil {
 		return "", errors.New("user reference is nil")
 	}
-	token := jwt.New(jwt.SigningMethodRS256)
+	var err error
+	token := jwt.New(signingKey.Method)
+	if len(signingKey.ID) > 0 {
+		token.Header["kid"] = signingKey.ID
+	}
 	claims := make(jwt.MapClaims)
 	claims["exp"] = time.Now().Add(expiration).Unix()
 	claims["iat"] = time.Now().Unix()
 	claims["sub"] = u.Username
+	if len(issuer) > 0 {
+		claims["iss"] = issuer
+	}
+	if len(audience) > 0 {
+		claims["aud"] = audience
+	}
+	claims["jti"], err = newTokenID()
+	if err != nil {
+		return "", err
+	}
 
 	claims["origin"] = u.Origin
 	claims["username"] = u.Username
@@ -74,7 +120,7 @@ func (u *User) GetJwt(signingKey *rsa.PrivateKey, expiration time.Duration) (str
 	claims["roles"] = u.Roles
 
 	token.Claims = claims
-	tokenString, err := token.SignedString(signingKey)
+	tokenString, err := token.SignedString(signingKey.Key)
 	if err != nil {
 		return "", err
 	}
@@ -83,13 +129,43 @@ func (u *User) GetJwt(signingKey *rsa.PrivateKey, expiration time.Duration) (str
 	return tokenString, nil
 }
 
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
 // RefreshJwt refreshes the token if needed
 func (u *User) RefreshJwt(signingKey *rsa.PrivateKey, expirationDuration time.Duration) (string, error) {
+	return u.refreshJwt(&SigningKey{Method: jwt.SigningMethodRS256, Key: signingKey}, expirationDuration, "", "")
+}
+
+// RefreshJwtWithKeyRing refreshes the token if needed, signing it with the key ring's current signing key
+func (u *User) RefreshJwtWithKeyRing(ring *KeyRing, expirationDuration time.Duration) (string, error) {
+	signingKey, err := ring.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	return u.refreshJwt(signingKey, expirationDuration, "", "")
+}
+
+// ReissueJwt refreshes the token if needed like RefreshJwt, setting the iss and aud claims of a new token to issuer and
+// audience unless they are empty
+func (u *User) ReissueJwt(signingKey *SigningKey, expirationDuration time.Duration, issuer string, audience string) (string, error) {
+	return u.refreshJwt(signingKey, expirationDuration, issuer, audience)
+}
+
+func (u *User) refreshJwt(signingKey *SigningKey, expirationDuration time.Duration, issuer string, audience string) (string, error) {
 	if u == nil {
 		return "", errors.New("user reference is nil")
 	}
 	if u.Token == nil {
-		return u.GetJwt(signingKey, expirationDuration)
+		return u.signJwt(signingKey, expirationDuration, issuer, audience)
 	}
 	token := u.Token
 	err := token.Claims.Valid()
@@ -110,10 +186,17 @@ func (u *User) RefreshJwt(signingKey *rsa.PrivateKey, expirationDuration time.Du
 	window := issued.Add(expirationDuration - (expirationDuration / 4))
 	glog.V(5).Infof("Token issued at %v, refresh window begins at %v.", issued, window)
 	if time.Now().After(window) {
-		return u.GetJwt(signingKey, expirationDuration)
+		return u.signJwt(signingKey, expirationDuration, issuer, audience)
 	}
 
-	tokenString, err := token.SignedString(signingKey)
+	token.Method = signingKey.Method
+	token.Header["alg"] = signingKey.Method.Alg()
+	if len(signingKey.ID) > 0 {
+		token.Header["kid"] = signingKey.ID
+	} else {
+		delete(token.Header, "kid")
+	}
+	tokenString, err := token.SignedString(signingKey.Key)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,133 @@
+package authentication
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+	"github.com/ticketmaster/authentication/common"
+)
+
+// Config is the configuration of a Manager. Clients, OIDC providers, trusted issuers, authorization rules and stores
+// are configured with the same maps their registered constructors take, see client.SupportedClients. If Strict is set
+// NewManager refuses to start on any problem Validate reports instead of skipping what fails to load.
+type Config struct {
+	Strict                bool
+	AuthenticationClients []map[interface{}]interface{} `mapstructure:"authenticationClient"`
+	OIDCProviders         []map[interface{}]interface{} `mapstructure:"oidc"`
+	TrustedIssuers        []map[interface{}]interface{}
+	Authorization         map[string]interface{}
+	JwtAlgorithm          string
+	PrivateKey            string
+	PublicKey             string
+	KeyID                 string
+	VerificationKeys      []common.VerificationKeyConfig
+	JwtExpiration         time.Duration
+	IssuerURL             string
+	Audience              string
+	EnableAnonymousAccess bool
+	Revocation            map[interface{}]interface{}
+	RefreshTokens         *RefreshTokenConfig
+}
+
+// RefreshTokenConfig enables refresh tokens
+type RefreshTokenConfig struct {
+	Expiration time.Duration
+	Store      map[interface{}]interface{}
+}
+
+// LoadConfig reads a Config from a YAML, JSON or TOML file. The format is chosen by the file extension.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	format := strings.TrimPrefix(filepath.Ext(path), ".")
+	if format == "yml" {
+		format = "yaml"
+	}
+
+	return ParseConfig(data, format)
+}
+
+// ParseConfig parses a Config in the yaml, json or toml format
+func ParseConfig(data []byte, format string) (*Config, error) {
+	switch format {
+	case "yaml", "json", "toml":
+	default:
+		return nil, fmt.Errorf("unsupported configuration format: %s", format)
+	}
+
+	v := viper.New()
+	v.SetConfigType(format)
+	err := v.ReadConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return ConfigFromViper(v)
+}
+
+// ConfigFromViper decodes a Config from the settings of a viper instance
+func ConfigFromViper(v *viper.Viper) (*Config, error) {
+	config := &Config{}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.StringToTimeDurationHookFunc(),
+		Result:     config,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = decoder.Decode(normalizeConfig(v.AllSettings()))
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %v", err)
+	}
+
+	return config, nil
+}
+
+// normalizeConfig converts values parsed from JSON or TOML to the types YAML produces, which the client and rule
+// constructors expect: maps keyed by interface{}, lists of interface{} and int for whole numbers
+func normalizeConfig(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		m := make(map[interface{}]interface{}, len(v))
+		for key, item := range v {
+			m[key] = normalizeConfig(item)
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(v))
+		for key, item := range v {
+			m[key] = normalizeConfig(item)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			list = append(list, normalizeConfig(item))
+		}
+		return list
+	case []map[string]interface{}:
+		list := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			list = append(list, normalizeConfig(item))
+		}
+		return list
+	case int64:
+		return int(v)
+	case float64:
+		if v == float64(int(v)) {
+			return int(v)
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,195 @@
+package authentication
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var yamlConfig = `
+authenticationClient:
+  - provider: memory
+    origin: yamlOrigin
+    allowPlaintextPasswords: true
+    users:
+      - username: test
+        password: testpass
+        roles:
+          - testRole
+authorization:
+  default: deny
+  cache:
+    size: 10
+  rules:
+    - ruleType: route
+      path: /api/*
+      authorize: allow
+      role: testRole
+      origin: ".*"
+privateKey: test-certificates/jwt.rsa
+publicKey: test-certificates/jwt.rsa.pub
+jwtExpiration: 1h
+refreshTokens:
+  expiration: 720h
+`
+
+var jsonConfig = `{
+  "authenticationClient": [
+    {"provider": "memory", "origin": "jsonOrigin", "allowPlaintextPasswords": true,
+     "users": [{"username": "test", "password": "testpass", "roles": ["testRole"]}]}
+  ],
+  "authorization": {
+    "default": "deny",
+    "cache": {"size": 10},
+    "rules": [{"ruleType": "route", "path": "/api/*", "authorize": "allow", "role": "testRole", "origin": ".*"}]
+  },
+  "privateKey": "test-certificates/jwt.rsa",
+  "publicKey": "test-certificates/jwt.rsa.pub",
+  "jwtExpiration": "1h",
+  "refreshTokens": {"expiration": "720h"}
+}`
+
+var tomlConfig = `
+privateKey = "test-certificates/jwt.rsa"
+publicKey = "test-certificates/jwt.rsa.pub"
+jwtExpiration = "1h"
+
+[[authenticationClient]]
+provider = "memory"
+origin = "tomlOrigin"
+allowPlaintextPasswords = true
+
+[[authenticationClient.users]]
+username = "test"
+password = "testpass"
+roles = ["testRole"]
+
+[authorization]
+default = "deny"
+
+[authorization.cache]
+size = 10
+
+[[authorization.rules]]
+ruleType = "route"
+path = "/api/*"
+authorize = "allow"
+role = "testRole"
+origin = ".*"
+
+[refreshTokens]
+expiration = "720h"
+`
+
+func TestParseConfig(t *testing.T) {
+	for format, data := range map[string]string{"yaml": yamlConfig, "json": jsonConfig, "toml": tomlConfig} {
+		config, err := ParseConfig([]byte(data), format)
+		if !assert.Nil(t, err, format) {
+			continue
+		}
+		assert.Equal(t, time.Hour, config.JwtExpiration, format)
+		assert.Equal(t, 720*time.Hour, config.RefreshTokens.Expiration, format)
+
+		m, err := NewManagerFromConfig(config)
+		if !assert.Nil(t, err, format) {
+			continue
+		}
+		u, err := m.ValidateCredentials("test", "testpass")
+		if assert.Nil(t, err, format) {
+			assert.Equal(t, format+"Origin", u.Origin)
+			assert.True(t, m.AuthorizeRequest(u, "", "/api/users", "GET"), format)
+		}
+		assert.NotNil(t, m.Authorization.Cache, format)
+		assert.NotNil(t, m.RefreshTokenStore, format)
+	}
+
+	_, err := ParseConfig([]byte(yamlConfig), "ini")
+	assert.EqualError(t, err, "unsupported configuration format: ini")
+
+	_, err = ParseConfig([]byte("jwtExpiration: soon"), "yaml")
+	assert.Error(t, err)
+
+	// Missing sections are errors, not panics
+	config, err := ParseConfig([]byte("privateKey: test-certificates/jwt.rsa\npublicKey: test-certificates/jwt.rsa.pub"), "yaml")
+	if assert.Nil(t, err) {
+		_, err = NewManagerFromConfig(config)
+		assert.EqualError(t, err, "invalid configuration:\njwtExpiration: must be specified")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "authentication.json")
+	err = ioutil.WriteFile(path, []byte(jsonConfig), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig(path)
+	if assert.Nil(t, err) {
+		assert.Equal(t, 1, len(config.AuthenticationClients))
+	}
+}
+
+func TestNewManagerFromConfig(t *testing.T) {
+	newConfig := func(origin string) *Config {
+		return &Config{
+			AuthenticationClients: []map[interface{}]interface{}{{
+				"provider":                "memory",
+				"origin":                  origin,
+				"allowPlaintextPasswords": true,
+				"users":                   []interface{}{map[interface{}]interface{}{"username": "test", "password": "testpass"}},
+			}},
+			PrivateKey:    "test-certificates/jwt.rsa",
+			PublicKey:     "test-certificates/jwt.rsa.pub",
+			JwtExpiration: time.Hour,
+		}
+	}
+
+	// Managers configured from code are independent of each other
+	first, err := NewManagerFromConfig(newConfig("first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewManagerFromConfig(newConfig("second"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := first.ValidateCredentials("test", "testpass")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "first", u.Origin)
+		token, err := first.GetJwt(u)
+		assert.Nil(t, err)
+		assert.Nil(t, second.LogoutRequest(RequestCredentials{Authorization: "Bearer " + token}, "", false))
+		_, err = first.CreateUserFromTokenString(token)
+		assert.Nil(t, err)
+	}
+	u, err = second.ValidateCredentials("test", "testpass")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "second", u.Origin)
+	}
+
+	// Unlike NewManager, components that fail to load are errors
+	config := newConfig("third")
+	config.AuthenticationClients[0]["provider"] = "ldap"
+	_, err = NewManagerFromConfig(config)
+	assert.Error(t, err)
+
+	config = newConfig("third")
+	config.Authorization = map[string]interface{}{"rules": []interface{}{map[interface{}]interface{}{"ruleType": "route"}}}
+	_, err = NewManagerFromConfig(config)
+	assert.Error(t, err)
+
+	_, err = NewManagerFromConfig(&Config{RefreshTokens: &RefreshTokenConfig{}, JwtExpiration: time.Hour})
+	assert.EqualError(t, err, "invalid configuration:\nrefreshTokens: invalid refresh token expiration: expiration must be specified")
+}
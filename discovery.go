@@ -0,0 +1,79 @@
+package authentication
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/golang/glog"
+	"github.com/ticketmaster/authentication/common"
+)
+
+// JwksPath is the path the JSON Web Key Set of the issued tokens is published at
+const JwksPath = "/.well-known/jwks.json"
+
+// DiscoveryPath is the path the OpenID discovery document is published at
+const DiscoveryPath = "/.well-known/openid-configuration"
+
+// DiscoveryDocument is the minimal OpenID provider metadata downstream services need to locate the verification keys
+type DiscoveryDocument struct {
+	Issuer                           string   `json:"issuer"`
+	JwksURI                          string   `json:"jwks_uri"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+	SubjectTypesSupported            []string `json:"subject_types_supported"`
+	ResponseTypesSupported           []string `json:"response_types_supported"`
+}
+
+// JWKS returns the public keys trusted to verify tokens issued by the manager as a JSON Web Key Set
+func (m Manager) JWKS() common.JSONWebKeySet {
+	set := common.JSONWebKeySet{Keys: []common.JSONWebKey{}}
+	if m.KeyRing == nil {
+		if m.PublicKey != nil {
+			jwk, _ := common.NewJSONWebKey(m.PublicKey)
+			jwk.Kid = jwk.Thumbprint()
+			jwk.Use = "sig"
+			jwk.Alg = "RS256"
+			set.Keys = append(set.Keys, jwk)
+		}
+		return set
+	}
+
+	for _, key := range m.KeyRing.VerificationKeys() {
+		jwk, err := common.NewJSONWebKey(key.Key)
+		if err != nil {
+			glog.Warningf("skipping verification key %s: %v", key.ID, err)
+			continue
+		}
+		jwk.Kid = key.ID
+		jwk.Use = "sig"
+		jwk.Alg = key.Method.Alg()
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+
+	return set
+}
+
+// DiscoveryDocument returns the discovery document of the configured IssuerURL. Without an IssuerURL there is no
+// document to publish and an error is returned.
+func (m Manager) DiscoveryDocument() (DiscoveryDocument, error) {
+	if len(m.IssuerURL) == 0 {
+		return DiscoveryDocument{}, errors.New("issuerURL must be configured to publish a discovery document")
+	}
+
+	algs := []string{}
+	seen := make(map[string]bool)
+	for _, jwk := range m.JWKS().Keys {
+		if !seen[jwk.Alg] {
+			seen[jwk.Alg] = true
+			algs = append(algs, jwk.Alg)
+		}
+	}
+
+	return DiscoveryDocument{
+		Issuer:                           m.IssuerURL,
+		JwksURI:                          m.IssuerURL + JwksPath,
+		IDTokenSigningAlgValuesSupported: algs,
+		SubjectTypesSupported:            []string{"public"},
+		ResponseTypesSupported:           []string{"id_token"},
+	}, nil
+}
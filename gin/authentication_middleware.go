@@ -2,132 +2,60 @@ package gin
 
 import (
 	"encoding/base64"
-	"errors"
 	"net/http"
 	"strings"
 
-	"github.com/ticketmaster/authentication/common"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/glog"
+	"github.com/ticketmaster/authentication"
+	"github.com/ticketmaster/authentication/common"
 )
 
 // Authentication provides the gin handler function to authenticate requests
 func Authentication() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if strings.ToLower(c.Request.RequestURI) == "/login" {
-			// Skip auth for login page
+		path := strings.ToLower(c.Request.URL.Path)
+		if path == "/login" || path == "/logout" || path == "/token/refresh" || strings.HasPrefix(path, "/login/oidc/") || (currentOptions.PublishKeys && (path == authentication.JwksPath || path == authentication.DiscoveryPath)) {
+			// Skip auth for login pages and published keys
 			c.Next()
 			return
 		}
 
 		session := sessions.Default(c)
-		var user *common.User
-		var err error
+		credentials := authentication.RequestCredentials{Authorization: c.Request.Header.Get("Authorization")}
 		// Validate JWT stores in session
-		jwtToken := session.Get("jwt")
-		glog.Infoln("Cookie:", jwtToken)
-		tokenString := ""
-		if jwtToken != nil {
-			tokenString = jwtToken.(string)
+		if tokenString, ok := session.Get("jwt").(string); ok {
+			credentials.SessionToken = tokenString
 		}
 
-		if currentOptions.EnableJwtAuthentication && len(tokenString) > 0 {
-			user, err = validateJwt(c, tokenString)
-			if err != nil {
-				c.AbortWithStatusJSON(http.StatusForbidden, map[string]string{"message": err.Error()})
-				return
-			}
-		} else if auth := c.Request.Header.Get("Authorization"); auth != "" {
-			authHeader := strings.Split(auth, " ")
-			switch authHeader[0] {
-			case "Bearer":
-				if !currentOptions.EnableJwtAuthentication {
-					err := errors.New("JWT provided, but is not enabled for authentication")
-					c.AbortWithStatusJSON(http.StatusForbidden, map[string]string{"message": err.Error()})
-					return
-				}
-
-				user, err = validateJwt(c, authHeader[1])
-				if err != nil {
-					c.AbortWithStatusJSON(http.StatusForbidden, map[string]string{"message": err.Error()})
-					return
-				}
-			case "Basic":
-				username, password, err := getCredentials(authHeader[1])
-				if err != nil {
-					c.AbortWithStatusJSON(http.StatusForbidden, map[string]string{"message": err.Error()})
-					return
-				}
-
-				user, err = currentOptions.manager.ValidateCredentials(username, password)
-				if err != nil {
-					if strings.Index(strings.ToLower(err.Error()), "invalid credentials") == -1 {
-						c.AbortWithStatusJSON(http.StatusForbidden, map[string]string{"message": err.Error()})
-						return
-					}
-
-					unauthorized(c, currentOptions)
-					return
-				}
-			default:
-				err := errors.New("unrecognized authentication header")
-				c.AbortWithStatusJSON(http.StatusForbidden, map[string]string{"message": err.Error()})
+		user, newToken, err := currentOptions.manager().Authenticate(credentials, currentOptions.requestOptions())
+		if err != nil {
+			if authErr, ok := err.(*authentication.AuthenticationError); ok && authErr.Challenge {
+				unauthorized(c, currentOptions)
 				return
 			}
-		} else {
-			if currentOptions.manager.EnableAnonymousAccess {
-				user, err = currentOptions.manager.CreateAnonymousUser()
-				if err != nil {
-					c.AbortWithStatusJSON(http.StatusForbidden, map[string]string{"message": err.Error()})
-					return
-				}
-			} else {
-				if currentOptions.manager.Authorization.Default != "allow" {
-					unauthorized(c, currentOptions)
-					return
-				}
-			}
-
-		}
 
-		if user != nil {
-			authorized := currentOptions.manager.IsAuthorized(user, map[string]string{"action": "", "route": c.Request.RequestURI, "method": c.Request.Method})
-			if !authorized {
-				c.AbortWithStatusJSON(http.StatusForbidden, map[string]string{"message": "Not authorized"})
-				return
-			}
-			setUserData(c, user)
-		} else {
-			err := errors.New("unrecognized user")
 			c.AbortWithStatusJSON(http.StatusForbidden, map[string]string{"message": err.Error()})
 			return
 		}
-		c.Next()
-	}
-}
 
-func validateJwt(c *gin.Context, tokenString string) (*common.User, error) {
-	if currentOptions.EnableJwtAuthentication && len(tokenString) > 0 {
-		user, err := currentOptions.manager.CreateUserFromTokenString(tokenString)
-		if err != nil {
-			return nil, err
+		if len(newToken) > 0 {
+			session.Set("jwt", newToken)
 		}
 
-		// Refresh token as needed
-		newTokenString, err := currentOptions.manager.RefreshJwt(user)
-		if err != nil {
-			return nil, err
+		decision := currentOptions.manager().EvaluateRequestWithHeaders(user, "", c.Request.URL.Path, c.Request.Method, c.Request.Header)
+		if currentOptions.ExposeDecision {
+			c.Header(authentication.DecisionHeader, decision.String())
+			glog.Infof("authorization decision for %s %s by %s: %v", c.Request.Method, c.Request.RequestURI, user.Username, decision)
 		}
-		if tokenString != newTokenString {
-			session := sessions.Default(c)
-			session.Set("jwt", newTokenString)
+		if !decision.Allow {
+			c.AbortWithStatusJSON(http.StatusForbidden, map[string]string{"message": "Not authorized"})
+			return
 		}
-
-		return user, nil
+		setUserData(c, user)
+		c.Next()
 	}
-
-	return nil, errors.New("no token string provided")
 }
 
 func setUserData(c *gin.Context, user *common.User) {
@@ -136,28 +64,15 @@ func setUserData(c *gin.Context, user *common.User) {
 	session.Set("name", user.Name)
 	session.Set("email", user.Email)
 	session.Set("roles", base64.StdEncoding.EncodeToString([]byte(strings.Join(user.Roles, ","))))
+	c.Set("user", user)
+	c.Request = c.Request.WithContext(common.NewContext(c.Request.Context(), user))
 }
 
 func unauthorized(c *gin.Context, currentOptions *AuthenticationOptions) {
-	if currentOptions.EnableBasicAuthentication {
-		c.Writer.Header().Add("WWW-Authenticate", `Basic realm="gin"`)
-	}
-
-	if currentOptions.EnableJwtAuthentication {
-		c.Writer.Header().Add("WWW-Authenticate", `Bearer`)
+	for _, challenge := range authentication.Challenges(currentOptions.requestOptions(), "gin") {
+		c.Writer.Header().Add("WWW-Authenticate", challenge)
 	}
 
 	glog.Infof(c.Writer.Header().Get("WWW-Authenticate"))
 	c.AbortWithStatusJSON(401, map[string]string{"message": "Not authorized"})
 }
-
-func getCredentials(data string) (username, password string, err error) {
-	decodedData, err := base64.StdEncoding.DecodeString(data)
-	if err != nil {
-		return "", "", err
-	}
-	strData := strings.Split(string(decodedData), ":")
-	username = strData[0]
-	password = strData[1]
-	return
-}
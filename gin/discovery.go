@@ -0,0 +1,23 @@
+package gin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS publishes the public keys that verify issued tokens as a JSON Web Key Set
+func JWKS(c *gin.Context) {
+	c.JSON(http.StatusOK, currentOptions.manager().JWKS())
+}
+
+// Discovery publishes a minimal OpenID discovery document of the configured issuer pointing at the JSON Web Key Set
+func Discovery(c *gin.Context) {
+	doc, err := currentOptions.manager().DiscoveryDocument()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, doc)
+}
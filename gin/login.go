@@ -7,6 +7,7 @@ import (
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/glog"
+	"github.com/ticketmaster/authentication"
 )
 
 type loginCommand struct {
@@ -34,14 +35,21 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	user, err := currentOptions.manager.ValidateCredentials(request.Username, request.Password)
+	user, err := currentOptions.manager().ValidateCredentials(request.Username, request.Password)
 	if err != nil {
 		glog.Error(err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
 		return
 	}
 
-	token, err := currentOptions.manager.GetJwt(user)
+	token, err := currentOptions.manager().GetJwt(user)
+	if err != nil {
+		glog.Error(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+		return
+	}
+
+	refreshToken, err := currentOptions.manager().IssueRefreshToken(user)
 	if err != nil {
 		glog.Error(err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
@@ -51,7 +59,81 @@ func Login(c *gin.Context) {
 	session := sessions.Default(c)
 	session.Set("jwt", token)
 	session.Save()
-	c.JSON(http.StatusOK, struct{ Token string }{token})
+	c.JSON(http.StatusOK, tokenResponse{token, refreshToken})
 	return
 
 }
+
+type tokenResponse struct {
+	Token        string
+	RefreshToken string `json:",omitempty"`
+}
+
+// RefreshToken provides an endpoint to exchange a refresh token for a new JWT token and a new refresh token
+func RefreshToken(c *gin.Context) {
+	if currentOptions.manager().RefreshTokenStore == nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, map[string]string{"message": "refresh tokens are not enabled"})
+		return
+	}
+
+	request := struct {
+		RefreshToken string
+	}{""}
+	err := c.BindJSON(&request)
+	if err != nil {
+		glog.Error(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+
+	_, token, refreshToken, err := currentOptions.manager().ExchangeRefreshToken(request.RefreshToken)
+	if err != nil {
+		glog.Error(err)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, map[string]string{"message": err.Error()})
+		return
+	}
+
+	session := sessions.Default(c)
+	session.Set("jwt", token)
+	session.Save()
+	c.JSON(http.StatusOK, tokenResponse{token, refreshToken})
+}
+
+// Logout provides an endpoint to revoke the current token and clear it from the session. A refresh token may be passed
+// to revoke it along with the token, and all revokes every token issued to the user.
+func Logout(c *gin.Context) {
+	request := struct {
+		RefreshToken string
+		All          bool
+	}{"", false}
+	if c.Request.ContentLength > 0 {
+		err := c.BindJSON(&request)
+		if err != nil {
+			glog.Error(err)
+			c.AbortWithStatusJSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+			return
+		}
+	}
+
+	session := sessions.Default(c)
+	credentials := authentication.RequestCredentials{Authorization: c.Request.Header.Get("Authorization")}
+	if tokenString, ok := session.Get("jwt").(string); ok {
+		credentials.SessionToken = tokenString
+	}
+	session.Delete("jwt")
+	session.Save()
+
+	err := currentOptions.manager().LogoutRequest(credentials, request.RefreshToken, request.All)
+	if err != nil {
+		if authErr, ok := err.(*authentication.AuthenticationError); ok && authErr.Challenge {
+			unauthorized(c, currentOptions)
+			return
+		}
+
+		glog.Error(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]string{"message": "Log out succeeded"})
+}
@@ -0,0 +1,71 @@
+package gin
+
+import (
+	"net/http"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/glog"
+	"github.com/ticketmaster/authentication/oidc"
+)
+
+// OIDCLogin redirects the browser to the OpenID Connect provider named in the route to begin the authorization code flow
+func OIDCLogin(c *gin.Context) {
+	provider, err := currentOptions.manager().GetOIDCProvider(c.Param("provider"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+		return
+	}
+
+	redirectURL, state, err := provider.BeginLogin()
+	if err != nil {
+		glog.Error(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+		return
+	}
+
+	session := sessions.Default(c)
+	session.Set("oidc_state", state.State)
+	session.Set("oidc_nonce", state.Nonce)
+	session.Set("oidc_verifier", state.CodeVerifier)
+	session.Save()
+	c.Redirect(http.StatusFound, redirectURL)
+}
+
+// OIDCCallback completes the authorization code flow, stores a JWT for the user in the session and redirects to the provider's post login page
+func OIDCCallback(c *gin.Context) {
+	provider, err := currentOptions.manager().GetOIDCProvider(c.Param("provider"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+		return
+	}
+
+	session := sessions.Default(c)
+	state := &oidc.LoginState{}
+	state.State, _ = session.Get("oidc_state").(string)
+	state.Nonce, _ = session.Get("oidc_nonce").(string)
+	state.CodeVerifier, _ = session.Get("oidc_verifier").(string)
+	session.Delete("oidc_state")
+	session.Delete("oidc_nonce")
+	session.Delete("oidc_verifier")
+
+	user, err := provider.CompleteLogin(state, c.Request.URL.Query())
+	if err != nil {
+		glog.Error(err)
+		session.Save()
+		c.AbortWithStatusJSON(http.StatusUnauthorized, map[string]string{"message": err.Error()})
+		return
+	}
+
+	token, err := currentOptions.manager().GetJwt(user)
+	if err != nil {
+		glog.Error(err)
+		session.Save()
+		c.AbortWithStatusJSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+		return
+	}
+
+	session.Set("jwt", token)
+	session.Save()
+	c.Redirect(http.StatusFound, provider.PostLoginRedirect)
+}
@@ -1,6 +1,8 @@
 package gin
 
 import (
+	"errors"
+
 	"github.com/ticketmaster/authentication"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
@@ -17,7 +19,11 @@ type AuthenticationOptions struct {
 	ConfigName                string
 	ConfigPath                string
 	EnvironmentVarPrefix      string
-	manager                   *authentication.Manager
+	PublishKeys               bool
+	ExposeDecision            bool
+	// WatchConfig reloads the configuration file when it changes, see authentication.ManagerReloader
+	WatchConfig bool
+	reloader    *authentication.ManagerReloader
 }
 
 // NewAuthenticationOptions creates a set of default authentication options
@@ -25,6 +31,15 @@ func NewAuthenticationOptions() *AuthenticationOptions {
 	return &AuthenticationOptions{EnableBasicAuthentication: true, EnableJwtAuthentication: true, ConfigName: "authentication", ConfigPath: "./", EnvironmentVarPrefix: "AUTH"}
 }
 
+// manager returns the current Manager
+func (o *AuthenticationOptions) manager() *authentication.Manager {
+	return o.reloader.Manager()
+}
+
+func (o *AuthenticationOptions) requestOptions() authentication.RequestOptions {
+	return authentication.RequestOptions{EnableJwtAuthentication: o.EnableJwtAuthentication, EnableBasicAuthentication: o.EnableBasicAuthentication}
+}
+
 // UseAuthentication is a helper function to set up authentication handlers and login API endpoints
 func UseAuthentication(r *gin.Engine, options *AuthenticationOptions) error {
 	viper.SetConfigName(options.ConfigName)
@@ -35,18 +50,36 @@ func UseAuthentication(r *gin.Engine, options *AuthenticationOptions) error {
 		return err
 	}
 
-	manager, err := authentication.NewManager()
+	reloader, err := authentication.NewManagerReloader()
 	if err != nil {
 		return err
 	}
+	if options.WatchConfig {
+		reloader.Watch()
+	}
+
+	manager := reloader.Manager()
+	if options.PublishKeys && len(manager.IssuerURL) == 0 {
+		return errors.New("issuerURL must be configured to publish keys")
+	}
 
-	options.manager = manager
+	options.reloader = reloader
 	currentOptions = options
 
 	store := cookie.NewStore([]byte("secretkey"))
 	r.Use(sessions.Sessions("auth-session", store))
 	r.Use(Authentication())
 
+	// Refresh and OIDC routes are always registered, since a reload can enable them. Their handlers answer 404 while
+	// the current Manager has them disabled.
 	r.POST("/login", Login)
+	r.POST("/logout", Logout)
+	r.POST("/token/refresh", RefreshToken)
+	r.GET("/login/oidc/:provider", OIDCLogin)
+	r.GET("/login/oidc/:provider/callback", OIDCCallback)
+	if options.PublishKeys {
+		r.GET(authentication.JwksPath, JWKS)
+		r.GET(authentication.DiscoveryPath, Discovery)
+	}
 	return nil
 }
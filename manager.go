@@ -2,15 +2,18 @@ package authentication
 
 import (
 	"crypto/rsa"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/ticketmaster/authentication/authorization"
 	"github.com/ticketmaster/authentication/client"
 	"github.com/ticketmaster/authentication/common"
+	"github.com/ticketmaster/authentication/oidc"
 	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/golang/glog"
 	"github.com/spf13/viper"
@@ -18,89 +21,226 @@ import (
 
 // Manager holds references to the authentication components
 type Manager struct {
-	AuthenticationClients []client.Client
-	Authorization         *authorization.Authorization
-	PrivateKey            *rsa.PrivateKey
-	PublicKey             *rsa.PublicKey
-	JwtExpiration         time.Duration
-	EnableAnonymousAccess bool
+	AuthenticationClients  []client.Client
+	Authorization          *authorization.Authorization
+	PrivateKey             *rsa.PrivateKey
+	PublicKey              *rsa.PublicKey
+	KeyRing                *common.KeyRing
+	JwtAlgorithm           string
+	JwtExpiration          time.Duration
+	IssuerURL              string
+	Audience               string
+	EnableAnonymousAccess  bool
+	OIDCProviders          map[string]*oidc.Provider
+	TrustedIssuers         []*common.TrustedIssuer
+	RefreshTokenStore      common.RefreshTokenStore
+	RefreshTokenExpiration time.Duration
+	RevocationStore        common.RevocationStore
 }
 
-// NewManager instantiates a new Manager struct from configuration
+// NewManager instantiates a new Manager struct from the configuration read by the global viper instance. Clients,
+// providers, issuers and authorization rules that fail to load are logged and skipped unless strict is set in the
+// configuration.
 func NewManager() (*Manager, error) {
-	manager := &Manager{}
+	return newManager(false)
+}
 
-	var configs = viper.Get("authenticationClient").([]interface{})
-	for idx, config := range configs {
-		provider := config.(map[interface{}]interface{})["provider"].(string)
-		glog.V(2).Infof("Detected configuration for authentication client type: %s at index %v", provider, idx)
-		added := false
-		for scName, sc := range client.SupportedClients {
-			if provider == scName {
-				client, err := sc(config.(map[interface{}]interface{}))
-				if err != nil {
-					glog.Errorf("error creating client at index %v: %v", idx, err)
-					continue
-				}
-				manager.AuthenticationClients = append(manager.AuthenticationClients, client)
-				added = true
-				break
+// NewManagerFromConfig instantiates a new Manager from a Config. Any problem Validate reports fails the whole
+// configuration with a ValidationError.
+func NewManagerFromConfig(config *Config) (*Manager, error) {
+	return newManagerFromConfig(config, true)
+}
+
+// newManager instantiates a new Manager from the global viper configuration. If strict is set any component that fails
+// to load fails the whole configuration.
+func newManager(strict bool) (*Manager, error) {
+	config, err := ConfigFromViper(viper.GetViper())
+	if err != nil {
+		return nil, err
+	}
+
+	return newManagerFromConfig(config, strict)
+}
+
+func newManagerFromConfig(config *Config, strict bool) (*Manager, error) {
+	manager, problems := buildManager(config)
+	for _, warning := range problems.warnings {
+		glog.Warning(warning)
+	}
+
+	if len(problems.errors) > 0 && (strict || config.Strict) {
+		manager.Close()
+		return nil, &ValidationError{Errors: problems.errors}
+	}
+	if problems.fatal != nil {
+		manager.Close()
+		return nil, problems.fatal
+	}
+	for _, e := range problems.errors {
+		glog.Error(e)
+	}
+
+	return manager, nil
+}
+
+// Close releases the resources held by the authentication clients and stores, such as SQL connection pools. The
+// Manager must not be used afterwards.
+func (m Manager) Close() error {
+	closers := []interface{}{m.RefreshTokenStore, m.RevocationStore}
+	for _, c := range m.AuthenticationClients {
+		closers = append(closers, c)
+	}
+
+	return closeAll(closers...)
+}
+
+// closeAll closes the values that implement io.Closer and returns their errors joined
+func closeAll(values ...interface{}) error {
+	var errs []string
+	for _, value := range values {
+		if closer, ok := value.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err.Error())
 			}
 		}
+	}
 
-		if !added {
-			glog.Warningf("could not find type for authentication client. Skipping client at index %v", idx)
-		}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
 	}
 
-	authorizationConfig := viper.Get("authorization")
-	if authorizationConfig == nil {
-		glog.Infof("no authorization section present in config")
-	} else {
-		authorization, err := authorization.NewAuthorization(authorizationConfig.(map[string]interface{}))
+	return nil
+}
+
+// buildManager creates every component of a Manager, skipping those that fail to load and collecting their problems
+func buildManager(config *Config) (*Manager, *configProblems) {
+	manager := &Manager{OIDCProviders: make(map[string]*oidc.Provider)}
+	problems := &configProblems{}
+
+	// Users can only hold the roles listed by the clients unless roles come from tokens of other issuers
+	origins := []string{}
+	roles := []string{}
+	rolesKnown := len(config.OIDCProviders) == 0 && len(config.TrustedIssuers) == 0
+	for idx, clientConfig := range config.AuthenticationClients {
+		path := fmt.Sprintf("authenticationClient[%v]", idx)
+		provider, _ := clientConfig["provider"].(string)
+		glog.V(2).Infof("Detected configuration for authentication client type: %s at index %v", provider, idx)
+		constructor, ok := client.SupportedClients[provider]
+		if len(provider) == 0 {
+			problems.add(path+".provider", "must be specified")
+			continue
+		}
+		if !ok {
+			problems.add(path+".provider", fmt.Sprintf("unknown provider: %s", provider))
+			continue
+		}
+
+		c, err := constructor(clientConfig)
 		if err != nil {
-			glog.Error(err)
+			problems.add(path, err)
+			continue
+		}
+		manager.AuthenticationClients = append(manager.AuthenticationClients, c)
+		origins = append(origins, c.GetOrigin())
+		if lister, ok := c.(client.RoleLister); ok {
+			roles = append(roles, lister.Roles()...)
 		} else {
-			manager.Authorization = authorization
+			rolesKnown = false
 		}
 	}
 
-	priv := viper.GetString("privateKey")
-	if len(priv) > 0 {
-		privBytes, err := ioutil.ReadFile(priv)
+	for idx, providerConfig := range config.OIDCProviders {
+		provider, err := oidc.NewProvider(providerConfig)
 		if err != nil {
-			return nil, err
+			problems.add(fmt.Sprintf("oidc[%v]", idx), err)
+			continue
 		}
-		privKey, err := jwt.ParseRSAPrivateKeyFromPEM(privBytes)
+		manager.OIDCProviders[provider.Name] = provider
+		origins = append(origins, provider.Origin)
+	}
+
+	for idx, issuerConfig := range config.TrustedIssuers {
+		issuer, err := common.NewTrustedIssuer(issuerConfig)
 		if err != nil {
-			return nil, err
+			problems.add(fmt.Sprintf("trustedIssuers[%v]", idx), err)
+			continue
 		}
+		manager.TrustedIssuers = append(manager.TrustedIssuers, issuer)
+		origins = append(origins, issuer.Origin)
+	}
 
-		manager.PrivateKey = privKey
+	if config.EnableAnonymousAccess {
+		origins = append(origins, "Anonymous")
+		roles = append(roles, "Anonymous")
+	}
+	if !rolesKnown {
+		roles = nil
 	}
 
-	pub := viper.GetString("publicKey")
-	if len(pub) > 0 {
-		pubBytes, err := ioutil.ReadFile(pub)
-		if err != nil {
-			return nil, err
+	if config.Authorization == nil {
+		glog.Infof("no authorization section present in config")
+	} else {
+		validationErrors := authorization.Validate(config.Authorization)
+		for _, e := range validationErrors {
+			problems.errors = append(problems.errors, "authorization."+e)
 		}
-		pubKey, err := jwt.ParseRSAPublicKeyFromPEM(pubBytes)
-		if err != nil {
-			return nil, err
+		auth, err := authorization.NewAuthorization(config.Authorization)
+		if err == nil {
+			manager.Authorization = auth
+			for _, w := range authorization.DeadRules(config.Authorization, origins, roles) {
+				problems.warnings = append(problems.warnings, "authorization."+w)
+			}
+		} else if len(validationErrors) == 0 {
+			// Validate locates the problems NewAuthorization fails on, but strict mode must fail even if it missed one
+			problems.add("authorization", err)
 		}
+	}
 
-		manager.PublicKey = pubKey
+	manager.JwtAlgorithm = config.JwtAlgorithm
+	if len(manager.JwtAlgorithm) == 0 {
+		manager.JwtAlgorithm = common.DefaultJwtAlgorithm
 	}
 
-	expiration, err := time.ParseDuration(viper.GetString("jwtExpiration"))
+	keyRing, err := common.LoadKeyRing(common.KeyRingConfig{
+		Algorithm:        manager.JwtAlgorithm,
+		PrivateKey:       config.PrivateKey,
+		PublicKey:        config.PublicKey,
+		KeyID:            config.KeyID,
+		VerificationKeys: config.VerificationKeys,
+	})
 	if err != nil {
-		return nil, err
+		problems.fail(err, keyPath(config, manager.JwtAlgorithm), err)
+	} else {
+		manager.KeyRing = keyRing
+		manager.setRSAKeys()
 	}
-	manager.JwtExpiration = expiration
-	manager.EnableAnonymousAccess = viper.GetBool("enableAnonymousAccess")
 
-	return manager, nil
+	if config.JwtExpiration <= 0 {
+		problems.fail(errors.New("jwtExpiration must be specified"), "jwtExpiration", "must be specified")
+	}
+	manager.JwtExpiration = config.JwtExpiration
+	manager.EnableAnonymousAccess = config.EnableAnonymousAccess
+	if len(config.IssuerURL) > 0 {
+		if u, err := url.Parse(config.IssuerURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || len(u.Host) == 0 {
+			problems.fail(errors.New("issuerURL must be an absolute http or https URL"), "issuerURL", "must be an absolute http or https URL")
+		}
+	}
+	manager.IssuerURL = strings.TrimSuffix(config.IssuerURL, "/")
+	manager.Audience = config.Audience
+
+	err = manager.loadRevocationStore(config.Revocation)
+	if err != nil {
+		problems.fail(err, "revocation", err)
+	}
+
+	if config.RefreshTokens != nil {
+		err = manager.loadRefreshTokenStore(config.RefreshTokens)
+		if err != nil {
+			problems.fail(err, "refreshTokens", err)
+		}
+	}
+
+	return manager, problems
 }
 
 // CreateAnonymousUser creates a User struct for anonymous users
@@ -140,24 +280,127 @@ func (m Manager) ValidateCredentials(username string, password string) (*common.
 	return u, nil
 }
 
+// GetOIDCProvider returns the configured OpenID Connect provider with the specified name
+func (m Manager) GetOIDCProvider(name string) (*oidc.Provider, error) {
+	provider, ok := m.OIDCProviders[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown oidc provider: %s", name)
+	}
+
+	return provider, nil
+}
+
 // CreateUserFromToken convers a Jwt into a User struct
 func (m Manager) CreateUserFromToken(token *jwt.Token) (*common.User, error) {
 	return common.CreateUserFromToken(token)
 }
 
-// CreateUserFromTokenString parses a Jwt token string and returns a User struct
+// CreateUserFromTokenString parses a Jwt token string and returns a User struct. Tokens from a trusted issuer are validated
+// against that issuer's keys, all other tokens against the manager's public key.
+// Revoked tokens are rejected.
 func (m Manager) CreateUserFromTokenString(tokenString string) (*common.User, error) {
-	return common.CreateUserFromTokenString(tokenString, m.PublicKey)
+	var u *common.User
+	var err error
+	if issuer := m.getTrustedIssuer(common.TokenIssuer(tokenString)); issuer != nil {
+		u, err = issuer.CreateUserFromTokenString(tokenString)
+	} else if m.KeyRing != nil {
+		u, err = common.CreateUserFromTokenStringWithKeyRing(tokenString, m.KeyRing)
+	} else {
+		u, err = common.CreateUserFromTokenString(tokenString, m.PublicKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if m.RevocationStore != nil {
+		claims, _ := u.Token.Claims.(jwt.MapClaims)
+		revoked, err := m.RevocationStore.IsRevoked(common.ClaimString(claims, "jti"), revocationUser(u), claimTime(claims, "iat"))
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, errors.New("token has been revoked")
+		}
+	}
+
+	return u, nil
 }
 
-// GetJwt gets a JWT for a given user
+func (m Manager) getTrustedIssuer(iss string) *common.TrustedIssuer {
+	if len(iss) == 0 {
+		return nil
+	}
+
+	for _, issuer := range m.TrustedIssuers {
+		if issuer.Issuer == iss {
+			return issuer
+		}
+	}
+
+	return nil
+}
+
+// GetJwt gets a JWT for a given user. Its iss claim is the IssuerURL and its aud claim the Audience, if configured.
 func (m Manager) GetJwt(u *common.User) (string, error) {
-	return u.GetJwt(m.PrivateKey, m.JwtExpiration)
+	signingKey, err := m.signingKey()
+	if err != nil {
+		return "", err
+	}
+
+	return u.IssueJwt(signingKey, m.JwtExpiration, m.IssuerURL, m.Audience)
 }
 
 // RefreshJwt refreshes a JWT for a given user. If the expiration window is not yet available, the existing token is returned.
+// Tokens from a trusted issuer are never refreshed.
 func (m Manager) RefreshJwt(u *common.User) (string, error) {
-	return u.RefreshJwt(m.PrivateKey, m.JwtExpiration)
+	if u != nil && u.Token != nil {
+		if claims, ok := u.Token.Claims.(jwt.MapClaims); ok && m.getTrustedIssuer(common.ClaimString(claims, "iss")) != nil {
+			return u.Token.Raw, nil
+		}
+	}
+
+	signingKey, err := m.signingKey()
+	if err != nil {
+		return "", err
+	}
+
+	return u.ReissueJwt(signingKey, m.JwtExpiration, m.IssuerURL, m.Audience)
+}
+
+// signingKey returns the current signing key of the key ring, or the RSA private key if there is no key ring
+func (m Manager) signingKey() (*common.SigningKey, error) {
+	if m.KeyRing != nil {
+		return m.KeyRing.SigningKey()
+	}
+
+	return &common.SigningKey{Method: jwt.SigningMethodRS256, Key: m.PrivateKey}, nil
+}
+
+// ReloadKeys reads the signing and verification keys from disk again. Tokens signed with keys that are no longer
+// configured stop validating; the current keys are kept if any key file cannot be read.
+func (m *Manager) ReloadKeys() error {
+	if m.KeyRing == nil {
+		return errors.New("no key ring configured")
+	}
+
+	err := m.KeyRing.Reload()
+	if err != nil {
+		return err
+	}
+
+	m.setRSAKeys()
+	return nil
+}
+
+// setRSAKeys exposes the key ring's current keys through PrivateKey and PublicKey when they are RSA keys
+func (m *Manager) setRSAKeys() {
+	m.PrivateKey, m.PublicKey = nil, nil
+	if signingKey, err := m.KeyRing.SigningKey(); err == nil {
+		m.PrivateKey, _ = signingKey.Key.(*rsa.PrivateKey)
+	}
+	if publicKey, err := m.KeyRing.VerificationKey(""); err == nil {
+		m.PublicKey, _ = publicKey.Key.(*rsa.PublicKey)
+	}
 }
 
 // IsAuthorized determines if a user is authorized for the specified action
@@ -165,3 +408,186 @@ func (m Manager) IsAuthorized(u *common.User, actions map[string]string) bool {
 
 	return m.Authorization.IsAuthorized(u, actions)
 }
+
+func (m *Manager) loadRefreshTokenStore(config *RefreshTokenConfig) error {
+	if config.Expiration <= 0 {
+		return errors.New("invalid refresh token expiration: expiration must be specified")
+	}
+	m.RefreshTokenExpiration = config.Expiration
+
+	provider, _ := config.Store["provider"].(string)
+	if len(provider) == 0 {
+		provider = "memory"
+	}
+	constructor, ok := common.SupportedRefreshTokenStores[provider]
+	if !ok {
+		return fmt.Errorf("unknown refresh token store: %s", provider)
+	}
+
+	var err error
+	m.RefreshTokenStore, err = constructor(config.Store)
+	return err
+}
+
+// IssueRefreshToken creates an opaque refresh token for the user that starts a new token family. An empty string is
+// returned if refresh tokens are not configured.
+func (m Manager) IssueRefreshToken(u *common.User) (string, error) {
+	if m.RefreshTokenStore == nil {
+		return "", nil
+	}
+
+	return m.issueRefreshToken(u, "")
+}
+
+func (m Manager) issueRefreshToken(u *common.User, family string) (string, error) {
+	tokenString, id, err := common.NewRefreshTokenString()
+	if err != nil {
+		return "", err
+	}
+
+	if len(family) == 0 {
+		family = id
+	}
+
+	err = m.RefreshTokenStore.Save(&common.RefreshToken{
+		ID:        id,
+		Family:    family,
+		Origin:    u.Origin,
+		Username:  u.Username,
+		Name:      u.Name,
+		Email:     u.Email,
+		Roles:     u.Roles,
+		IssuedAt:  time.Now(),
+		ExpiresAt: time.Now().Add(m.RefreshTokenExpiration),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return tokenString, nil
+}
+
+// ExchangeRefreshToken rotates a refresh token, returning the user together with a new access token and a new refresh
+// token. Presenting a refresh token a second time revokes every token of its family.
+func (m Manager) ExchangeRefreshToken(refreshToken string) (*common.User, string, string, error) {
+	if m.RefreshTokenStore == nil {
+		return nil, "", "", errors.New("refresh tokens are not enabled")
+	}
+
+	stored, err := m.RefreshTokenStore.Consume(common.RefreshTokenID(refreshToken))
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	if m.RevocationStore != nil {
+		revoked, err := m.RevocationStore.IsRevoked("", revocationUser(stored.User()), stored.IssuedAt)
+		if err != nil {
+			return nil, "", "", err
+		}
+		if revoked {
+			return nil, "", "", common.ErrRefreshTokenNotFound
+		}
+	}
+
+	if stored.Used {
+		glog.Warningf("refresh token reuse detected for user %s, revoking token family", stored.Username)
+		err = m.RefreshTokenStore.RevokeFamily(stored.Family)
+		if err != nil {
+			glog.Error(err)
+		}
+		return nil, "", "", common.ErrRefreshTokenReused
+	}
+
+	u := stored.User()
+	token, err := m.GetJwt(u)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	newRefreshToken, err := m.issueRefreshToken(u, stored.Family)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	return u, token, newRefreshToken, nil
+}
+
+func (m *Manager) loadRevocationStore(config map[interface{}]interface{}) error {
+	provider, _ := config["provider"].(string)
+	if len(provider) == 0 {
+		provider = "memory"
+	}
+	constructor, ok := common.SupportedRevocationStores[provider]
+	if !ok {
+		return fmt.Errorf("unknown revocation store: %s", provider)
+	}
+
+	if config == nil {
+		config = make(map[interface{}]interface{})
+	}
+
+	var err error
+	m.RevocationStore, err = constructor(config)
+	return err
+}
+
+func revocationUser(u *common.User) string {
+	return u.Origin + "/" + u.Username
+}
+
+func claimTime(claims jwt.MapClaims, name string) time.Time {
+	switch v := claims[name].(type) {
+	case float64:
+		return time.Unix(int64(v), 0)
+	case int64:
+		return time.Unix(v, 0)
+	case json.Number:
+		i, _ := v.Int64()
+		return time.Unix(i, 0)
+	}
+
+	return time.Time{}
+}
+
+// Logout revokes the user's current token and, if given, the refresh token family it belongs to. If all is set every
+// token issued to the user so far is revoked, including refresh tokens.
+func (m Manager) Logout(u *common.User, refreshToken string, all bool) error {
+	if m.RevocationStore == nil {
+		return errors.New("token revocation is not enabled")
+	}
+
+	if u.Token != nil {
+		claims, _ := u.Token.Claims.(jwt.MapClaims)
+		if jti := common.ClaimString(claims, "jti"); len(jti) > 0 {
+			expiresAt := claimTime(claims, "exp")
+			if expiresAt.IsZero() {
+				expiresAt = time.Now().Add(m.JwtExpiration)
+			}
+			err := m.RevocationStore.RevokeToken(jti, expiresAt)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(refreshToken) > 0 && m.RefreshTokenStore != nil {
+		stored, err := m.RefreshTokenStore.Consume(common.RefreshTokenID(refreshToken))
+		if err == nil {
+			err = m.RefreshTokenStore.RevokeFamily(stored.Family)
+		}
+		if err != nil && err != common.ErrRefreshTokenNotFound {
+			return err
+		}
+	}
+
+	if all {
+		ttl := m.JwtExpiration
+		if m.RefreshTokenExpiration > ttl {
+			ttl = m.RefreshTokenExpiration
+		}
+		now := time.Now()
+		return m.RevocationStore.RevokeUser(revocationUser(u), now, now.Add(ttl))
+	}
+
+	return nil
+}
@@ -2,13 +2,16 @@ package authentication
 
 import (
 	"bytes"
+	"encoding/json"
 	"testing"
 	"time"
 
+	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/ticketmaster/authentication/authorization"
 	"github.com/ticketmaster/authentication/client"
+	"github.com/ticketmaster/authentication/common"
 )
 
 var validManagerConfig = []byte(`
@@ -22,6 +25,7 @@ authenticationClient:
     tlsServerName: my.mydomain.com
   - provider: memory
     origin: testOrigin
+    allowPlaintextPasswords: true
     users:
         - username: test
           password: testpass
@@ -55,6 +59,12 @@ authorization:
 privateKey: test-certificates/jwt.rsa
 publicKey: test-certificates/jwt.rsa.pub
 jwtExpiration: 1h
+issuerURL: https://api.example.com/
+audience: example
+refreshTokens:
+  expiration: 720h
+  store:
+    provider: memory
 `)
 
 var manager *Manager
@@ -77,8 +87,11 @@ func TestNewManager(t *testing.T) {
 	}
 	assert.Equal(t, privateKey, mgr.PrivateKey)
 	assert.Equal(t, publicKey, mgr.PublicKey)
+	assert.NotNil(t, mgr.KeyRing)
 	assert.Equal(t, time.Duration(1*time.Hour), mgr.JwtExpiration)
 	assert.Equal(t, false, mgr.EnableAnonymousAccess)
+	assert.NotNil(t, mgr.RefreshTokenStore)
+	assert.Equal(t, 720*time.Hour, mgr.RefreshTokenExpiration)
 
 	manager = mgr
 }
@@ -135,9 +148,192 @@ func TestIsAuthorized(t *testing.T) {
 	authorized = manager.IsAuthorized(u, map[string]string{"route": "/test"})
 	assert.Equal(t, false, authorized)
 
-	priorAction := manager.Authorization.Rules[0].(*authorization.ActionRule).Action[0]
-	manager.Authorization.Rules[0].(*authorization.ActionRule).Action[0] = "MyTestAction"
+	priorRules := manager.Authorization.Rules
+	rule, err := authorization.NewActionRule(map[interface{}]interface{}{"action": "MyTestAction", "authorize": "allow", "role": "testRole", "origin": "testOrigin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := append(priorRules[:0:0], priorRules...)
+	rules[0] = rule
+	manager.Authorization.SetRules(rules)
 	authorized = manager.IsAuthorized(u, map[string]string{"route": "/test", "action": "Home.Index"})
 	assert.Equal(t, false, authorized)
-	manager.Authorization.Rules[0].(*authorization.ActionRule).Action[0] = priorAction
+	manager.Authorization.SetRules(priorRules)
+}
+
+func TestGetJwt(t *testing.T) {
+	u, err := manager.ValidateCredentials("test", "testpass")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tokenString, err := manager.GetJwt(u)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	signingKey, err := manager.KeyRing.SigningKey()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	token, _, err := new(jwt.Parser).ParseUnverified(tokenString, jwt.MapClaims{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.Equal(t, signingKey.ID, token.Header["kid"])
+	assert.Equal(t, "https://api.example.com", token.Claims.(jwt.MapClaims)["iss"])
+	assert.Equal(t, "example", token.Claims.(jwt.MapClaims)["aud"])
+
+	u2, err := manager.CreateUserFromTokenString(tokenString)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.Equal(t, u.Username, u2.Username)
+	assert.NoError(t, manager.ReloadKeys())
+}
+
+func TestJWKS(t *testing.T) {
+	set := manager.JWKS()
+	if !assert.Equal(t, 1, len(set.Keys)) {
+		return
+	}
+	signingKey, err := manager.KeyRing.SigningKey()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.Equal(t, signingKey.ID, set.Keys[0].Kid)
+	assert.Equal(t, "RS256", set.Keys[0].Alg)
+	assert.Equal(t, "sig", set.Keys[0].Use)
+
+	// Downstream services can verify issued tokens with the published keys
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	keys, err := common.NewStaticKeySet(data)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	key, err := keys.Key(signingKey.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, manager.PublicKey, key)
+
+	doc, err := manager.DiscoveryDocument()
+	assert.Nil(t, err)
+	assert.Equal(t, "https://api.example.com", doc.Issuer)
+	assert.Equal(t, "https://api.example.com/.well-known/jwks.json", doc.JwksURI)
+	assert.Equal(t, []string{"RS256"}, doc.IDTokenSigningAlgValuesSupported)
+
+	_, err = Manager{}.DiscoveryDocument()
+	assert.EqualError(t, err, "issuerURL must be configured to publish a discovery document")
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	u, err := manager.ValidateCredentials("test", "testpass")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	refreshToken, err := manager.IssueRefreshToken(u)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	u2, token, rotated, err := manager.ExchangeRefreshToken(refreshToken)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.Equal(t, u.Username, u2.Username)
+	assert.Equal(t, u.Roles, u2.Roles)
+	assert.NotEqual(t, refreshToken, rotated)
+	u3, err := manager.CreateUserFromTokenString(token)
+	if assert.NoError(t, err) {
+		assert.Equal(t, u.Username, u3.Username)
+	}
+
+	// Reusing an exchanged token revokes the whole family
+	_, _, _, err = manager.ExchangeRefreshToken(refreshToken)
+	assert.Equal(t, common.ErrRefreshTokenReused, err)
+	_, _, _, err = manager.ExchangeRefreshToken(rotated)
+	assert.Equal(t, common.ErrRefreshTokenNotFound, err)
+
+	_, _, _, err = manager.ExchangeRefreshToken("unknown")
+	assert.Equal(t, common.ErrRefreshTokenNotFound, err)
+}
+
+func TestLogout(t *testing.T) {
+	u, err := manager.ValidateCredentials("test", "testpass")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tokenString, err := manager.GetJwt(u)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	other, err := manager.GetJwt(&common.User{Origin: u.Origin, Username: u.Username, Roles: u.Roles})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	refreshToken, err := manager.IssueRefreshToken(u)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Logging out revokes the presented token and refresh token only
+	err = manager.LogoutRequest(RequestCredentials{Authorization: "Bearer " + tokenString}, refreshToken, false)
+	assert.NoError(t, err)
+	_, err = manager.CreateUserFromTokenString(tokenString)
+	assert.EqualError(t, err, "token has been revoked")
+	_, err = manager.CreateUserFromTokenString(other)
+	assert.NoError(t, err)
+	_, _, _, err = manager.ExchangeRefreshToken(refreshToken)
+	assert.Equal(t, common.ErrRefreshTokenNotFound, err)
+
+	err = manager.LogoutRequest(RequestCredentials{Authorization: "Bearer " + tokenString}, "", false)
+	assert.Error(t, err)
+
+	// Logging out everywhere revokes all tokens issued so far
+	u2, err := manager.ValidateCredentials("test2", "testpass2")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	tokenString, _ = manager.GetJwt(u2)
+	other, _ = manager.GetJwt(&common.User{Origin: u2.Origin, Username: u2.Username})
+	refreshToken, err = manager.IssueRefreshToken(u2)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = manager.LogoutRequest(RequestCredentials{SessionToken: tokenString}, "", true)
+	assert.NoError(t, err)
+	_, err = manager.CreateUserFromTokenString(other)
+	assert.Error(t, err)
+	_, _, _, err = manager.ExchangeRefreshToken(refreshToken)
+	assert.Error(t, err)
+
+	// Refresh tokens issued after the logout stay valid
+	refreshToken, err = manager.IssueRefreshToken(u2)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, _, _, err = manager.ExchangeRefreshToken(refreshToken)
+	assert.NoError(t, err)
 }
@@ -0,0 +1,81 @@
+package nethttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/ticketmaster/authentication"
+	"github.com/ticketmaster/authentication/common"
+)
+
+// Authentication returns a middleware that authenticates and authorizes requests and places the user in the request context
+func Authentication(manager *authentication.Manager, options *AuthenticationOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.ToLower(r.URL.Path) == strings.ToLower(options.LoginPath) {
+				// Skip auth for login page
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			credentials := authentication.RequestCredentials{Authorization: r.Header.Get("Authorization")}
+			if cookie, err := r.Cookie(options.CookieName); err == nil {
+				credentials.SessionToken = cookie.Value
+			}
+
+			user, newToken, err := manager.Authenticate(credentials, options.requestOptions())
+			if err != nil {
+				if authErr, ok := err.(*authentication.AuthenticationError); ok && authErr.Challenge {
+					unauthorized(w, options)
+					return
+				}
+
+				writeMessage(w, http.StatusForbidden, err.Error())
+				return
+			}
+
+			if len(newToken) > 0 && len(credentials.SessionToken) > 0 {
+				setTokenCookie(w, options, newToken)
+			}
+
+			if !manager.EvaluateRequestWithHeaders(user, "", r.URL.Path, r.Method, r.Header).Allow {
+				writeMessage(w, http.StatusForbidden, "Not authorized")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(common.NewContext(r.Context(), user)))
+		})
+	}
+}
+
+// UserFromRequest returns the user placed in the request context by the Authentication middleware
+func UserFromRequest(r *http.Request) (*common.User, bool) {
+	return common.FromContext(r.Context())
+}
+
+func setTokenCookie(w http.ResponseWriter, options *AuthenticationOptions, token string) {
+	http.SetCookie(w, &http.Cookie{Name: options.CookieName, Value: token, Path: "/", HttpOnly: true})
+}
+
+func unauthorized(w http.ResponseWriter, options *AuthenticationOptions) {
+	for _, c := range authentication.Challenges(options.requestOptions(), options.Realm) {
+		w.Header().Add("WWW-Authenticate", c)
+	}
+
+	writeMessage(w, http.StatusUnauthorized, "Not authorized")
+}
+
+func writeMessage(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(body)
+	if err != nil {
+		glog.Error(err)
+	}
+}
@@ -0,0 +1,163 @@
+package nethttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var validConfig = []byte(`
+authenticationClient:
+  - provider: memory
+    origin: testOrigin
+    allowPlaintextPasswords: true
+    users:
+        - username: test
+          password: testpass
+          name: My Name
+          email: test@test.com
+          roles:
+              - testRole
+        - username: test2
+          password: testpass2
+          name: My Name2
+          email: test2@test.com
+
+authorization:
+  default: deny
+  rules:
+    - ruleType: route
+      route:
+        - /test
+      authorize: allow
+      role: testRole
+      origin: testOrigin
+    - ruleType: route
+      path: /public/*
+      authorize: allow
+      role: testRole
+      origin: testOrigin
+    - ruleType: route
+      path: /public/admin/*
+      authorize: deny
+      role: testRole
+      origin: testOrigin
+
+privateKey: ../test-certificates/jwt.rsa
+publicKey: ../test-certificates/jwt.rsa.pub
+jwtExpiration: 1h
+`)
+
+func newTestHandler(t *testing.T) http.Handler {
+	manager, err := getManager(validConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := UserFromRequest(r)
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(user.Username))
+	})
+
+	return Handler(manager, NewAuthenticationOptions(), handler)
+}
+
+func TestAuthenticationBasic(t *testing.T) {
+	handler := newTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.SetBasicAuth("test", "testpass")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "test", rec.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.SetBasicAuth("test", "invalidpass")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, []string{`Basic realm="api"`, "Bearer"}, rec.Header()["Www-Authenticate"])
+
+	req = httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.SetBasicAuth("test2", "testpass2")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/test", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Authorization", "Digest abc")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestLoginAndBearer(t *testing.T) {
+	handler := newTestHandler(t)
+
+	body, _ := json.Marshal(map[string]string{"username": "test", "password": "testpass"})
+	req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if !assert.Equal(t, http.StatusOK, rec.Code) {
+		return
+	}
+
+	var response struct{ Token string }
+	err := json.NewDecoder(rec.Body).Decode(&response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, response.Token)
+	assert.Equal(t, response.Token, rec.Result().Cookies()[0].Value)
+
+	req = httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+response.Token)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "test", rec.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.AddCookie(&http.Cookie{Name: "jwt", Value: response.Token})
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Authorization", "Bearer invalid")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestAuthorizationDecodedPath(t *testing.T) {
+	handler := newTestHandler(t)
+
+	for uri, code := range map[string]int{
+		"/public/users":           http.StatusOK,
+		"/public/admin/users":     http.StatusForbidden,
+		"/public/adm%69n/users":   http.StatusForbidden,
+		"/public/./admin/users":   http.StatusForbidden,
+		"/public//admin/users?x=": http.StatusForbidden,
+	} {
+		req := httptest.NewRequest(http.MethodGet, uri, nil)
+		req.SetBasicAuth("test", "testpass")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, code, rec.Code, uri)
+	}
+}
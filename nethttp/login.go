@@ -0,0 +1,63 @@
+package nethttp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/golang/glog"
+	"github.com/ticketmaster/authentication"
+)
+
+// Login returns a handler that provides an endpoint to log in and receive a JWT token
+func Login(manager *authentication.Manager, options *AuthenticationOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeMessage(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		request := struct {
+			Username string
+			Password string
+		}{"", ""}
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			glog.Error(err)
+			writeMessage(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if !options.EnableJwtAuthentication {
+			err := errors.New("JWT authentication is disabled")
+			glog.Error(err)
+			writeMessage(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		user, err := manager.ValidateCredentials(request.Username, request.Password)
+		if err != nil {
+			glog.Error(err)
+			writeMessage(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		token, err := manager.GetJwt(user)
+		if err != nil {
+			glog.Error(err)
+			writeMessage(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		setTokenCookie(w, options, token)
+		writeJSON(w, http.StatusOK, struct{ Token string }{token})
+	})
+}
+
+// Handler wraps the handler with the Authentication middleware and mounts the Login endpoint at the configured login path
+func Handler(manager *authentication.Manager, options *AuthenticationOptions, handler http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(options.LoginPath, Login(manager, options))
+	mux.Handle("/", handler)
+	return Authentication(manager, options)(mux)
+}
@@ -0,0 +1,18 @@
+package nethttp
+
+import (
+	"bytes"
+
+	"github.com/spf13/viper"
+	"github.com/ticketmaster/authentication"
+)
+
+func getManager(config []byte) (*authentication.Manager, error) {
+	viper.SetConfigType("yaml")
+	err := viper.ReadConfig(bytes.NewBuffer(config))
+	if err != nil {
+		return nil, err
+	}
+
+	return authentication.NewManager()
+}
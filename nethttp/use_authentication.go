@@ -0,0 +1,39 @@
+package nethttp
+
+import (
+	"github.com/spf13/viper"
+	"github.com/ticketmaster/authentication"
+)
+
+// AuthenticationOptions is a struct that holds options relating to the authentication package
+type AuthenticationOptions struct {
+	EnableJwtAuthentication   bool
+	EnableBasicAuthentication bool
+	ConfigName                string
+	ConfigPath                string
+	LoginPath                 string
+	CookieName                string
+	Realm                     string
+}
+
+// NewAuthenticationOptions creates a set of default authentication options
+func NewAuthenticationOptions() *AuthenticationOptions {
+	return &AuthenticationOptions{EnableBasicAuthentication: true, EnableJwtAuthentication: true, ConfigName: "authentication", ConfigPath: "./", LoginPath: "/login", CookieName: "jwt", Realm: "api"}
+}
+
+func (o *AuthenticationOptions) requestOptions() authentication.RequestOptions {
+	return authentication.RequestOptions{EnableJwtAuthentication: o.EnableJwtAuthentication, EnableBasicAuthentication: o.EnableBasicAuthentication}
+}
+
+// NewManager reads the configuration file named in the options and creates a Manager from it
+func NewManager(options *AuthenticationOptions) (*authentication.Manager, error) {
+	viper.SetConfigName(options.ConfigName)
+	viper.AddConfigPath(options.ConfigPath)
+	viper.SetEnvPrefix("AUTH")
+	err := viper.ReadInConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return authentication.NewManager()
+}
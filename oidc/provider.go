@@ -0,0 +1,284 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/mitchellh/mapstructure"
+	"github.com/ticketmaster/authentication/common"
+)
+
+// Provider is an OpenID Connect issuer used to log users in with the authorization code flow and PKCE
+type Provider struct {
+	Name              string
+	Origin            string
+	Issuer            string
+	ClientID          string
+	ClientSecret      string
+	RedirectURL       string
+	Scopes            []string
+	Claims            common.ClaimMapping
+	PostLoginRedirect string
+	HTTPClient        *http.Client
+
+	mutex     sync.Mutex
+	discovery *discoveryDocument
+	keys      *common.KeySet
+}
+
+// LoginState holds the values that must be kept (e.g. in the session) between redirecting to the issuer and handling the callback
+type LoginState struct {
+	State        string
+	Nonce        string
+	CodeVerifier string
+}
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+// NewProvider creates a new Provider from the specified configuration
+func NewProvider(config map[interface{}]interface{}) (*Provider, error) {
+	p := &Provider{}
+	err := mapstructure.Decode(config, p)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []string
+	if len(p.Name) == 0 {
+		errs = append(errs, "name must be specified in configuration")
+	}
+	if len(p.Issuer) == 0 {
+		errs = append(errs, "issuer must be specified in configuration")
+	}
+	if len(p.ClientID) == 0 {
+		errs = append(errs, "clientID must be specified in configuration")
+	}
+	if len(p.RedirectURL) == 0 {
+		errs = append(errs, "redirectURL must be specified in configuration")
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("errors occurred creating oidc provider: %v", strings.Join(errs, "\n"))
+	}
+
+	p.Issuer = strings.TrimSuffix(p.Issuer, "/")
+	if len(p.Origin) == 0 {
+		p.Origin = p.Name
+	}
+	if len(p.Scopes) == 0 {
+		p.Scopes = []string{"openid", "profile", "email"}
+	}
+	p.Claims.SetDefaults()
+	if len(p.PostLoginRedirect) == 0 {
+		p.PostLoginRedirect = "/"
+	}
+	if p.HTTPClient == nil {
+		p.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return p, nil
+}
+
+func (p *Provider) getDiscovery() (*discoveryDocument, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	resp, err := p.HTTPClient.Get(p.Issuer + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status fetching discovery document: %s", resp.Status)
+	}
+
+	doc := &discoveryDocument{}
+	err = json.NewDecoder(resp.Body).Decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSuffix(doc.Issuer, "/") != p.Issuer {
+		return nil, fmt.Errorf("discovery document issuer %s does not match configured issuer %s", doc.Issuer, p.Issuer)
+	}
+
+	keys := common.NewRemoteKeySet(doc.JwksURI)
+	keys.HTTPClient = p.HTTPClient
+	p.discovery = doc
+	p.keys = keys
+	return doc, nil
+}
+
+// BeginLogin creates a new login state and returns the issuer URL the browser should be redirected to
+func (p *Provider) BeginLogin() (string, *LoginState, error) {
+	doc, err := p.getDiscovery()
+	if err != nil {
+		return "", nil, err
+	}
+
+	state := &LoginState{}
+	for _, v := range []*string{&state.State, &state.Nonce, &state.CodeVerifier} {
+		*v, err = randomString()
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	challenge := sha256.Sum256([]byte(state.CodeVerifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.ClientID},
+		"redirect_uri":          {p.RedirectURL},
+		"scope":                 {strings.Join(p.Scopes, " ")},
+		"state":                 {state.State},
+		"nonce":                 {state.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(doc.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return doc.AuthorizationEndpoint + separator + query.Encode(), state, nil
+}
+
+// CompleteLogin validates the callback parameters against the login state, exchanges the authorization code and
+// returns the User described by the validated ID token
+func (p *Provider) CompleteLogin(state *LoginState, callback url.Values) (*common.User, error) {
+	if state == nil || len(state.State) == 0 {
+		return nil, errors.New("no login in progress")
+	}
+
+	if e := callback.Get("error"); len(e) > 0 {
+		return nil, fmt.Errorf("issuer returned error: %s %s", e, callback.Get("error_description"))
+	}
+
+	if callback.Get("state") != state.State {
+		return nil, errors.New("state parameter does not match")
+	}
+
+	code := callback.Get("code")
+	if len(code) == 0 {
+		return nil, errors.New("no authorization code provided")
+	}
+
+	idToken, err := p.exchange(code, state.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.validateIDToken(idToken, state.Nonce)
+}
+
+func (p *Provider) exchange(code string, codeVerifier string) (string, error) {
+	doc, err := p.getDiscovery()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.RedirectURL},
+		"client_id":     {p.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+	req, err := http.NewRequest(http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if len(p.ClientSecret) > 0 {
+		req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	}
+
+	resp, err := p.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token exchange failed: %s %s", token.Error, token.ErrorDescription)
+	}
+
+	if len(token.IDToken) == 0 {
+		return "", errors.New("token response did not include an id_token")
+	}
+
+	return token.IDToken, nil
+}
+
+func (p *Provider) validateIDToken(idToken string, nonce string) (*common.User, error) {
+	token, err := jwt.Parse(idToken, func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		default:
+			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return p.keys.Key(kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	if _, ok := claims["exp"]; !ok {
+		return nil, errors.New("id token does not have an expiration")
+	}
+
+	if !claims.VerifyIssuer(p.Issuer, true) {
+		return nil, errors.New("id token issuer does not match")
+	}
+
+	if !common.HasAudience(claims, p.ClientID) {
+		return nil, errors.New("id token audience does not match")
+	}
+
+	if n, _ := claims["nonce"].(string); n != nonce {
+		return nil, errors.New("id token nonce does not match")
+	}
+
+	return p.Claims.CreateUser(p.Origin, claims), nil
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
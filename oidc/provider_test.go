@@ -0,0 +1,193 @@
+package oidc
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubIssuer is a minimal OpenID Connect issuer that accepts a single authorization code
+type stubIssuer struct {
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+func newStubIssuer(t *testing.T) *stubIssuer {
+	privBytes, err := ioutil.ReadFile("../test-certificates/jwt.rsa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(privBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &stubIssuer{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 s.server.URL,
+			"authorization_endpoint": s.server.URL + "/authorize",
+			"token_endpoint":         s.server.URL + "/token",
+			"jwks_uri":               s.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "stub",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if r.PostForm.Get("code") != "stubcode" || base64.RawURLEncoding.EncodeToString(verifier[:]) != s.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		claims := jwt.MapClaims{"iss": s.server.URL, "aud": []string{"client"}, "sub": "1234", "nonce": s.nonce, "exp": time.Now().Add(time.Minute).Unix()}
+		for k, v := range s.claims {
+			if v == nil {
+				delete(claims, k)
+			} else {
+				claims[k] = v
+			}
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "stub"
+		idToken, _ := token.SignedString(key)
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idToken, "token_type": "Bearer"})
+	})
+	s.server = httptest.NewServer(mux)
+	return s
+}
+
+// authorize simulates the browser visiting the authorization endpoint and returns the callback parameters
+func (s *stubIssuer) authorize(t *testing.T, redirectURL string) url.Values {
+	u, err := url.Parse(redirectURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "/authorize", u.Path)
+	assert.Equal(t, "S256", u.Query().Get("code_challenge_method"))
+	s.challenge = u.Query().Get("code_challenge")
+	s.nonce = u.Query().Get("nonce")
+	return url.Values{"code": {"stubcode"}, "state": {u.Query().Get("state")}}
+}
+
+func newTestProvider(t *testing.T, issuer string) *Provider {
+	p, err := NewProvider(map[interface{}]interface{}{
+		"name":        "stub",
+		"origin":      "testOrigin",
+		"issuer":      issuer,
+		"clientID":    "client",
+		"redirectURL": "http://localhost/login/oidc/stub/callback",
+		"claims":      map[interface{}]interface{}{"roles": "roles"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestNewProvider(t *testing.T) {
+	p := newTestProvider(t, "https://issuer.example.com/")
+	assert.Equal(t, "https://issuer.example.com", p.Issuer)
+	assert.Equal(t, "testOrigin", p.Origin)
+	assert.Equal(t, []string{"openid", "profile", "email"}, p.Scopes)
+	assert.Equal(t, "preferred_username", p.Claims.Username)
+	assert.Equal(t, "roles", p.Claims.Roles)
+	assert.Equal(t, "/", p.PostLoginRedirect)
+
+	_, err := NewProvider(map[interface{}]interface{}{"name": "stub"})
+	assert.Error(t, err)
+}
+
+func TestLogin(t *testing.T) {
+	issuer := newStubIssuer(t)
+	defer issuer.server.Close()
+	issuer.claims = jwt.MapClaims{"preferred_username": "test", "email": "test@test.com", "roles": []string{"testRole", "testRole2"}}
+
+	p := newTestProvider(t, issuer.server.URL)
+	redirectURL, state, err := p.BeginLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	callback := issuer.authorize(t, redirectURL)
+	user, err := p.CompleteLogin(state, callback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "testOrigin", user.Origin)
+	assert.Equal(t, "test", user.Username)
+	assert.Equal(t, "test@test.com", user.Email)
+	assert.Equal(t, []string{"testRole", "testRole2"}, user.Roles)
+
+	// The state must match the login in progress
+	redirectURL, state, err = p.BeginLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	callback = issuer.authorize(t, redirectURL)
+	callback.Set("state", "forged")
+	_, err = p.CompleteLogin(state, callback)
+	assert.EqualError(t, err, "state parameter does not match")
+
+	// A code verifier that does not match the challenge is rejected by the issuer
+	callback = issuer.authorize(t, redirectURL)
+	state.CodeVerifier = "wrong"
+	_, err = p.CompleteLogin(state, callback)
+	assert.EqualError(t, err, "token exchange failed: invalid_grant ")
+
+	// The ID token nonce must match the login in progress
+	redirectURL, state, err = p.BeginLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	callback = issuer.authorize(t, redirectURL)
+	issuer.nonce = "replayed"
+	_, err = p.CompleteLogin(state, callback)
+	assert.EqualError(t, err, "id token nonce does not match")
+
+	// The ID token must be issued for this client
+	redirectURL, state, err = p.BeginLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	callback = issuer.authorize(t, redirectURL)
+	issuer.claims["aud"] = "otherClient"
+	_, err = p.CompleteLogin(state, callback)
+	assert.EqualError(t, err, "id token audience does not match")
+
+	// ID tokens without an expiration are rejected
+	redirectURL, state, err = p.BeginLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	callback = issuer.authorize(t, redirectURL)
+	issuer.claims["aud"] = "client"
+	issuer.claims["exp"] = nil
+	_, err = p.CompleteLogin(state, callback)
+	assert.EqualError(t, err, "id token does not have an expiration")
+}
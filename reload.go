@@ -0,0 +1,149 @@
+package authentication
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+)
+
+// ReloadEvent reports the outcome of reloading the configuration. On failure Err is set and Manager is the previous
+// Manager, which stays in use.
+type ReloadEvent struct {
+	Time    time.Time
+	Manager *Manager
+	Err     error
+}
+
+// ManagerReloader holds the current Manager and atomically replaces it when the configuration is reloaded. Reloaded
+// configurations are loaded strictly: if any client, provider, issuer or rule fails to load the previous Manager is
+// kept. Revocation and refresh token stores are carried over unless their configuration changed, so logouts and refresh
+// token families survive a reload.
+type ManagerReloader struct {
+	current   atomic.Value
+	mutex     sync.Mutex
+	stores    map[string]interface{}
+	listeners []func(ReloadEvent)
+}
+
+// NewManagerReloader creates a Manager from the configuration viper has read
+func NewManagerReloader() (*ManagerReloader, error) {
+	manager, err := NewManager()
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := ConfigFromViper(viper.GetViper())
+	if err != nil {
+		return nil, err
+	}
+
+	r := &ManagerReloader{stores: storeConfigs(config)}
+	r.current.Store(manager)
+	return r, nil
+}
+
+// Manager returns the current Manager
+func (r *ManagerReloader) Manager() *Manager {
+	return r.current.Load().(*Manager)
+}
+
+// OnReload registers a listener called after every reload attempt
+func (r *ManagerReloader) OnReload(listener func(ReloadEvent)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.listeners = append(r.listeners, listener)
+}
+
+// Watch reloads the configuration whenever the config file viper read changes
+func (r *ManagerReloader) Watch() {
+	viper.OnConfigChange(func(e fsnotify.Event) {
+		glog.Infof("authentication configuration %s changed, reloading", e.Name)
+		r.Reload()
+	})
+	viper.WatchConfig()
+}
+
+// Reload reads the config file again and swaps in a new Manager. On error the previous Manager is kept.
+func (r *ManagerReloader) Reload() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	previous := r.Manager()
+	manager, stores, err := loadManager()
+	if err != nil {
+		glog.Errorf("error reloading authentication configuration, keeping previous configuration: %v", err)
+		r.notify(ReloadEvent{Time: time.Now(), Manager: previous, Err: err})
+		return err
+	}
+
+	// The superseded Manager is closed after the swap, except for the stores carried over
+	superseded := *previous
+	if reflect.DeepEqual(stores["revocation"], r.stores["revocation"]) {
+		closeAll(manager.RevocationStore)
+		manager.RevocationStore = previous.RevocationStore
+		superseded.RevocationStore = nil
+	}
+	if reflect.DeepEqual(stores["refreshTokens"], r.stores["refreshTokens"]) && manager.RefreshTokenStore != nil && previous.RefreshTokenStore != nil {
+		closeAll(manager.RefreshTokenStore)
+		manager.RefreshTokenStore = previous.RefreshTokenStore
+		superseded.RefreshTokenStore = nil
+	}
+
+	r.stores = stores
+	r.current.Store(manager)
+	if err := superseded.Close(); err != nil {
+		glog.Warningf("error closing the previous authentication configuration: %v", err)
+	}
+	glog.Info("reloaded authentication configuration")
+	r.notify(ReloadEvent{Time: time.Now(), Manager: manager})
+	return nil
+}
+
+func (r *ManagerReloader) notify(event ReloadEvent) {
+	for _, listener := range r.listeners {
+		listener(event)
+	}
+}
+
+// loadManager reads the config file and strictly loads a Manager from it. Panics on malformed configuration are
+// returned as errors so that a bad edit cannot take down a running server.
+func loadManager() (manager *Manager, stores map[string]interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("invalid configuration: %v", p)
+		}
+	}()
+
+	err = viper.ReadInConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	config, err := ConfigFromViper(viper.GetViper())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	manager, err = NewManagerFromConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return manager, storeConfigs(config), nil
+}
+
+// storeConfigs returns the configuration of the stateful stores
+func storeConfigs(config *Config) map[string]interface{} {
+	stores := map[string]interface{}{"revocation": config.Revocation}
+	if config.RefreshTokens != nil {
+		stores["refreshTokens"] = config.RefreshTokens.Store
+	}
+
+	return stores
+}
@@ -0,0 +1,120 @@
+package authentication
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/ticketmaster/authentication/client"
+	"github.com/ticketmaster/authentication/common"
+)
+
+var reloadConfig = `
+authenticationClient:
+  - provider: memory
+    origin: testOrigin
+    allowPlaintextPasswords: true
+    users:
+      - username: test
+        password: testpass
+        roles:
+          - testRole
+  - provider: sql
+    driver: closetest
+    dsn: test
+    passwordQuery: SELECT 1
+authorization:
+  default: deny
+  rules:
+    - ruleType: route
+      path: /api/*
+      authorize: ALLOW
+      role: testRole
+      origin: testOrigin
+
+privateKey: test-certificates/jwt.rsa
+publicKey: test-certificates/jwt.rsa.pub
+jwtExpiration: 1h
+`
+
+func TestManagerReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func() {
+		viper.SetConfigFile("")
+		viper.SetConfigType("yaml")
+		viper.ReadConfig(bytes.NewBuffer(validManagerConfig))
+	}()
+
+	path := filepath.Join(dir, "authentication.yaml")
+	write := func(config string) {
+		err := ioutil.WriteFile(path, []byte(config), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(strings.Replace(reloadConfig, "ALLOW", "allow", 1))
+	viper.SetConfigFile(path)
+	err = viper.ReadInConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reloader, err := NewManagerReloader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []ReloadEvent
+	reloader.OnReload(func(e ReloadEvent) { events = append(events, e) })
+
+	user := &common.User{Origin: "testOrigin", Username: "test", Roles: []string{"testRole"}}
+	first := reloader.Manager()
+	assert.True(t, first.AuthorizeRequest(user, "", "/api/users", "GET"))
+	first.RevocationStore.RevokeToken("revoked", time.Now().Add(time.Hour))
+
+	// An invalid rule keeps the previous Manager
+	write(reloadConfig)
+	assert.Error(t, reloader.Reload())
+	assert.Equal(t, first, reloader.Manager())
+	if assert.Equal(t, 1, len(events)) {
+		assert.Error(t, events[0].Err)
+		assert.Equal(t, first, events[0].Manager)
+	}
+
+	// So does malformed configuration
+	write("authorization: [")
+	assert.Error(t, reloader.Reload())
+	write(strings.Replace(reloadConfig, "1h", "soon", 1))
+	assert.Error(t, reloader.Reload())
+	assert.Equal(t, first, reloader.Manager())
+
+	write(strings.Replace(reloadConfig, "ALLOW", "deny", 1))
+	assert.Nil(t, reloader.Reload())
+	second := reloader.Manager()
+	assert.NotEqual(t, first, second)
+	assert.False(t, second.AuthorizeRequest(user, "", "/api/users", "GET"))
+	revoked, _ := second.RevocationStore.IsRevoked("revoked", "", time.Now())
+	assert.True(t, revoked)
+	// The clients of the superseded Manager are closed
+	assert.EqualError(t, first.AuthenticationClients[1].(*client.SQLClient).DB.Ping(), "sql: database is closed")
+	assert.EqualError(t, second.AuthenticationClients[1].(*client.SQLClient).DB.Ping(), "not supported")
+	assert.Nil(t, events[len(events)-1].Err)
+
+	// Watching picks up edits to the file
+	reloader.Watch()
+	write(strings.Replace(reloadConfig, "ALLOW", "allow", 1))
+	deadline := time.Now().Add(5 * time.Second)
+	for reloader.Manager() == second && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, reloader.Manager().AuthorizeRequest(user, "", "/api/users", "GET"))
+}
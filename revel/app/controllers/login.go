@@ -2,8 +2,11 @@ package controllers
 
 import (
 	"errors"
+	"net/http"
 
 	"github.com/revel/revel"
+	"github.com/ticketmaster/authentication"
+	"github.com/ticketmaster/authentication/oidc"
 	module "github.com/ticketmaster/authentication/revel"
 )
 
@@ -20,52 +23,226 @@ func (c Authentication) Login() revel.Result {
 	}{"", ""}
 	err := c.Params.BindJSON(&request)
 	if err != nil {
-		revel.ERROR.Println(err)
+		c.Log.Error(err.Error())
 		return c.RenderError(err)
 	}
 
 	config, err := module.CreateAuthenticationConfig()
 	if err != nil {
-		revel.ERROR.Println(err)
+		c.Log.Error(err.Error())
 		return c.RenderError(err)
 	}
 
 	if !config.EnableJwtAuthentication {
 		err := errors.New("JWT authentication is disabled")
-		revel.ERROR.Println(err)
+		c.Log.Error(err.Error())
 		return c.RenderError(err)
 	}
 
-	user, err := config.AuthenticationManager.ValidateCredentials(request.Username, request.Password)
+	user, err := config.Manager().ValidateCredentials(request.Username, request.Password)
 	if err != nil {
-		revel.ERROR.Println(err)
+		c.Log.Error(err.Error())
 		return c.RenderError(err)
 	}
 
-	token, err := config.AuthenticationManager.GetJwt(user)
+	token, err := config.Manager().GetJwt(user)
 	if err != nil {
-		revel.ERROR.Println(err)
+		c.Log.Error(err.Error())
+		return c.RenderError(err)
+	}
+
+	refreshToken, err := config.Manager().IssueRefreshToken(user)
+	if err != nil {
+		c.Log.Error(err.Error())
+		return c.RenderError(err)
+	}
+
+	c.Controller.Session["jwt"] = token
+	return c.RenderJSON(tokenResponse{token, refreshToken})
+}
+
+type tokenResponse struct {
+	Token        string
+	RefreshToken string `json:",omitempty"`
+}
+
+// Refresh exchanges a refresh token for a new JWT and a new refresh token
+func (c Authentication) Refresh() revel.Result {
+	request := struct {
+		RefreshToken string
+	}{""}
+	err := c.Params.BindJSON(&request)
+	if err != nil {
+		c.Log.Error(err.Error())
+		c.Response.Status = http.StatusBadRequest
+		return c.RenderError(err)
+	}
+
+	config, err := module.CreateAuthenticationConfig()
+	if err != nil {
+		c.Log.Error(err.Error())
+		return c.RenderError(err)
+	}
+
+	if config.Manager().RefreshTokenStore == nil {
+		return c.NotFound("refresh tokens are not enabled")
+	}
+
+	_, token, refreshToken, err := config.Manager().ExchangeRefreshToken(request.RefreshToken)
+	if err != nil {
+		c.Log.Error(err.Error())
+		c.Response.Status = http.StatusUnauthorized
 		return c.RenderError(err)
 	}
 
 	c.Controller.Session["jwt"] = token
-	return c.RenderJSON(struct{ Token string }{token})
+	return c.RenderJSON(tokenResponse{token, refreshToken})
 }
 
-// Logout clears the JWT with this session
+// Logout revokes the current JWT and clears it from this session. A refresh token may be passed to revoke it along with
+// the JWT, and all revokes every token issued to the user.
 func (c Authentication) Logout() revel.Result {
+	request := struct {
+		RefreshToken string
+		All          bool
+	}{"", false}
+	if len(c.Params.JSON) > 0 {
+		err := c.Params.BindJSON(&request)
+		if err != nil {
+			c.Log.Error(err.Error())
+			c.Response.Status = http.StatusBadRequest
+			return c.RenderError(err)
+		}
+	}
+
 	config, err := module.CreateAuthenticationConfig()
 	if err != nil {
-		revel.ERROR.Println(err)
+		c.Log.Error(err.Error())
 		return c.RenderError(err)
 	}
 
 	if !config.EnableJwtAuthentication {
 		err := errors.New("JWT authentication is disabled")
-		revel.ERROR.Println(err)
+		c.Log.Error(err.Error())
 		return c.RenderError(err)
 	}
 
+	credentials := authentication.RequestCredentials{Authorization: c.Request.Header.Get("Authorization")}
+	if tokenString, ok := c.Session["jwt"].(string); ok {
+		credentials.SessionToken = tokenString
+	}
 	c.Controller.Session["jwt"] = ""
+
+	err = config.Manager().LogoutRequest(credentials, request.RefreshToken, request.All)
+	if err != nil {
+		if authErr, ok := err.(*authentication.AuthenticationError); ok && authErr.Challenge {
+			c.Response.Status = http.StatusUnauthorized
+			return c.RenderError(errors.New("401: Not authorized"))
+		}
+
+		c.Log.Error(err.Error())
+		return c.RenderError(err)
+	}
+
 	return c.RenderJSON(struct{ Message string }{"Log out succeeded"})
 }
+
+// OIDCLogin redirects the browser to the OpenID Connect provider to begin the authorization code flow
+func (c Authentication) OIDCLogin(provider string) revel.Result {
+	config, err := module.CreateAuthenticationConfig()
+	if err != nil {
+		c.Log.Error(err.Error())
+		return c.RenderError(err)
+	}
+
+	p, err := config.Manager().GetOIDCProvider(provider)
+	if err != nil {
+		c.Response.Status = http.StatusNotFound
+		return c.RenderError(err)
+	}
+
+	redirectURL, state, err := p.BeginLogin()
+	if err != nil {
+		c.Log.Error(err.Error())
+		return c.RenderError(err)
+	}
+
+	c.Session["oidc_state"] = state.State
+	c.Session["oidc_nonce"] = state.Nonce
+	c.Session["oidc_verifier"] = state.CodeVerifier
+	return c.Redirect(redirectURL)
+}
+
+// OIDCCallback completes the authorization code flow, stores a JWT for the user in the session and redirects to the provider's post login page
+func (c Authentication) OIDCCallback(provider string) revel.Result {
+	config, err := module.CreateAuthenticationConfig()
+	if err != nil {
+		c.Log.Error(err.Error())
+		return c.RenderError(err)
+	}
+
+	p, err := config.Manager().GetOIDCProvider(provider)
+	if err != nil {
+		c.Response.Status = http.StatusNotFound
+		return c.RenderError(err)
+	}
+
+	state := &oidc.LoginState{}
+	state.State, _ = c.Session["oidc_state"].(string)
+	state.Nonce, _ = c.Session["oidc_nonce"].(string)
+	state.CodeVerifier, _ = c.Session["oidc_verifier"].(string)
+	delete(c.Session, "oidc_state")
+	delete(c.Session, "oidc_nonce")
+	delete(c.Session, "oidc_verifier")
+
+	user, err := p.CompleteLogin(state, c.Params.Query)
+	if err != nil {
+		c.Log.Error(err.Error())
+		c.Response.Status = http.StatusUnauthorized
+		return c.RenderError(err)
+	}
+
+	token, err := config.Manager().GetJwt(user)
+	if err != nil {
+		c.Log.Error(err.Error())
+		return c.RenderError(err)
+	}
+
+	c.Session["jwt"] = token
+	return c.Redirect(p.PostLoginRedirect)
+}
+
+// JWKS publishes the public keys that verify issued tokens as a JSON Web Key Set
+func (c Authentication) JWKS() revel.Result {
+	config, err := module.CreateAuthenticationConfig()
+	if err != nil {
+		c.Log.Error(err.Error())
+		return c.RenderError(err)
+	}
+
+	if !config.PublishKeys {
+		return c.NotFound("key publishing is disabled")
+	}
+
+	return c.RenderJSON(config.Manager().JWKS())
+}
+
+// Discovery publishes a minimal OpenID discovery document of the configured issuer pointing at the JSON Web Key Set
+func (c Authentication) Discovery() revel.Result {
+	config, err := module.CreateAuthenticationConfig()
+	if err != nil {
+		c.Log.Error(err.Error())
+		return c.RenderError(err)
+	}
+
+	if !config.PublishKeys {
+		return c.NotFound("key publishing is disabled")
+	}
+
+	doc, err := config.Manager().DiscoveryDocument()
+	if err != nil {
+		return c.NotFound(err.Error())
+	}
+
+	return c.RenderJSON(doc)
+}
@@ -1,6 +1,8 @@
 package revel
 
 import (
+	"errors"
+
 	"github.com/revel/revel"
 	"github.com/spf13/viper"
 	"github.com/ticketmaster/authentication"
@@ -10,20 +12,38 @@ import (
 type AuthenticationConfig struct {
 	EnableJwtAuthentication   bool
 	EnableBasicAuthentication bool
-	AuthenticationManager     *authentication.Manager
+	PublishKeys               bool
+	ExposeDecision            bool
+	// AuthenticationManager is the Manager loaded at startup, use Manager for the current one
+	AuthenticationManager *authentication.Manager
+	Reloader              *authentication.ManagerReloader
 }
 
 var config *AuthenticationConfig
 
+// RequestOptions returns the authentication methods enabled for requests
+func (c *AuthenticationConfig) RequestOptions() authentication.RequestOptions {
+	return authentication.RequestOptions{EnableJwtAuthentication: c.EnableJwtAuthentication, EnableBasicAuthentication: c.EnableBasicAuthentication}
+}
+
+// Manager returns the current Manager, which changes on reload if authentication.watchConfig is enabled
+func (c *AuthenticationConfig) Manager() *authentication.Manager {
+	if c.Reloader == nil {
+		return c.AuthenticationManager
+	}
+
+	return c.Reloader.Manager()
+}
+
 // CreateAuthenticationConfig reads config files and prepares the authentication middleware for use
 func CreateAuthenticationConfig() (*AuthenticationConfig, error) {
 	if config != nil {
 		return config, nil
 	}
 
-	config := revel.Config.StringDefault("authentication.config.name", "authentication")
+	configName := revel.Config.StringDefault("authentication.config.name", "authentication")
 	configPath := revel.Config.StringDefault("authentication.config.path", "./conf")
-	viper.SetConfigName(config)
+	viper.SetConfigName(configName)
 	viper.AddConfigPath(configPath)
 	viper.SetEnvPrefix("AUTH")
 	err := viper.ReadInConfig()
@@ -31,12 +51,21 @@ func CreateAuthenticationConfig() (*AuthenticationConfig, error) {
 		return nil, err
 	}
 
-	manager, err := authentication.NewManager()
+	reloader, err := authentication.NewManagerReloader()
 	if err != nil {
 		return nil, err
 	}
+	publishKeys := revel.Config.BoolDefault("authentication.publishKeys", false)
+	if publishKeys && len(reloader.Manager().IssuerURL) == 0 {
+		return nil, errors.New("issuerURL must be configured to publish keys")
+	}
+	if revel.Config.BoolDefault("authentication.watchConfig", false) {
+		reloader.Watch()
+	}
 
 	jwt := revel.Config.BoolDefault("authentication.enableJwtAuth", true)
 	basic := revel.Config.BoolDefault("authentication.enableBasicAuth", true)
-	return &AuthenticationConfig{jwt, basic, manager}, nil
+	exposeDecision := revel.Config.BoolDefault("authentication.exposeDecision", false)
+	config = &AuthenticationConfig{jwt, basic, publishKeys, exposeDecision, reloader.Manager(), reloader}
+	return config, nil
 }
@@ -1,169 +1,90 @@
 package revel
 
 import (
-	"encoding/base64"
 	"errors"
 	"net/http"
-	"strings"
-
-	"github.com/ticketmaster/authentication/common"
 
 	"github.com/revel/revel"
+	"github.com/ticketmaster/authentication"
+	"github.com/ticketmaster/authentication/common"
 )
 
 // ValidateCredentials will prompt for credentials and validate them
 func ValidateCredentials(c *revel.Controller, filterChain []revel.Filter) {
-	if c.Action == "Authentication.Login" {
-		// Skip auth for login page
+	switch c.Action {
+	case "Authentication.Login", "Authentication.Logout", "Authentication.Refresh", "Authentication.OIDCLogin", "Authentication.OIDCCallback", "Authentication.JWKS", "Authentication.Discovery":
+		// Skip auth for login pages and published keys
 		filterChain[0](c, filterChain[1:]) // Execute the next filter stage.
 		return
 	}
 
 	config, err := CreateAuthenticationConfig()
 	if err != nil {
-		revel.ERROR.Println(err)
+		revel.AppLog.Error(err.Error())
 		c.Result = c.RenderError(err)
 		return
 	}
 
-	var user *common.User
+	credentials := authentication.RequestCredentials{Authorization: c.Request.Header.Get("Authorization")}
 	// Validate JWT stores in session
-	tokenString := c.Session["jwt"]
-	if config.EnableJwtAuthentication && len(tokenString) > 0 {
-		user, err = validateJwt(c, tokenString)
-		if err != nil {
-			revel.ERROR.Println(err)
-			c.Result = c.RenderError(err)
-			return
-		}
-	} else if auth := c.Request.Header.Get("Authorization"); auth != "" {
-		authHeader := strings.Split(auth, " ")
-		switch authHeader[0] {
-		case "Bearer":
-			if !config.EnableJwtAuthentication {
-				err := errors.New("JWT provided, but is not enabled for authentication")
-				revel.ERROR.Println(err)
-				c.Result = c.RenderError(err)
-				return
-			}
-
-			user, err = validateJwt(c, authHeader[1])
-			if err != nil {
-				revel.ERROR.Println(err)
-				c.Result = c.RenderError(err)
-				return
-			}
-		case "Basic":
-			username, password, err := getCredentials(authHeader[1])
-			if err != nil {
-				revel.ERROR.Println(err)
-				c.Result = c.RenderError(err)
-				return
-			}
-
-			user, err = config.AuthenticationManager.ValidateCredentials(username, password)
-			if err != nil {
-				if strings.Index(strings.ToLower(err.Error()), "invalid credentials") == -1 {
-					revel.ERROR.Println(err)
-					c.Result = c.RenderError(err)
-					return
-				}
+	if tokenString, ok := c.Session["jwt"].(string); ok {
+		credentials.SessionToken = tokenString
+	}
 
-				unauthorized(c, config)
-				return
-			}
-		default:
-			err := errors.New("unrecognized authentication header")
-			revel.ERROR.Println(err)
-			c.Result = c.RenderError(err)
+	user, newToken, err := config.Manager().Authenticate(credentials, config.RequestOptions())
+	if err != nil {
+		if authErr, ok := err.(*authentication.AuthenticationError); ok && authErr.Challenge {
+			unauthorized(c, config)
 			return
 		}
-	} else {
-		if config.AuthenticationManager.EnableAnonymousAccess {
-			user, err = config.AuthenticationManager.CreateAnonymousUser()
-			if err != nil {
-				revel.ERROR.Println(err)
-				c.Result = c.RenderError(err)
-				return
-			}
-		} else {
-			if config.AuthenticationManager.Authorization.Default != "allow" {
-				unauthorized(c, config)
-				return
-			}
-		}
 
+		revel.AppLog.Error(err.Error())
+		c.Result = c.RenderError(err)
+		return
 	}
 
-	if user != nil {
-		authorized := config.AuthenticationManager.IsAuthorized(user, map[string]string{"action": c.Action, "route": c.Request.RequestURI, "method": c.Request.Method})
-		if !authorized {
-			c.Response.Status = http.StatusUnauthorized
-			c.Result = c.RenderError(errors.New("401: Not authorized"))
-			return
-		}
-		setUserData(c, user)
-	} else {
-		err = errors.New("unrecognized user")
-		revel.ERROR.Println(err)
-		c.Result = c.RenderError(err)
+	if len(newToken) > 0 {
+		c.Session["jwt"] = newToken
+	}
+
+	decision := config.Manager().EvaluateRequestWithHeaders(user, c.Action, c.Request.GetPath(), c.Request.Method, requestHeader(c))
+	if config.ExposeDecision {
+		c.Response.Out.Header().Set(authentication.DecisionHeader, decision.String())
+		revel.AppLog.Infof("authorization decision for %s by %s: %v", c.Action, user.Username, decision)
+	}
+	if !decision.Allow {
+		c.Response.Status = http.StatusUnauthorized
+		c.Result = c.RenderError(errors.New("401: Not authorized"))
 		return
 	}
+	setUserData(c, user)
 	filterChain[0](c, filterChain[1:]) // Execute the next filter stage.
 }
 
-func validateJwt(c *revel.Controller, tokenString string) (*common.User, error) {
-	config, err := CreateAuthenticationConfig()
-	if err != nil {
-		return nil, err
+// requestHeader copies the request headers for expression rules
+func requestHeader(c *revel.Controller) http.Header {
+	header := http.Header{}
+	if c.Request.Header == nil || c.Request.Header.Server == nil {
+		return header
 	}
-
-	if config.EnableJwtAuthentication && len(tokenString) > 0 {
-		user, err := config.AuthenticationManager.CreateUserFromTokenString(tokenString)
-		if err != nil {
-			return nil, err
-		}
-
-		// Refresh token as needed
-		newTokenString, err := config.AuthenticationManager.RefreshJwt(user)
-		if err != nil {
-			return nil, err
-		}
-		if tokenString != newTokenString {
-			c.Session["jwt"] = newTokenString
-		}
-
-		return user, nil
+	for _, key := range c.Request.Header.Server.GetKeys() {
+		header[key] = c.Request.Header.GetAll(key)
 	}
 
-	return nil, errors.New("no token string provided")
+	return header
 }
 
 func setUserData(c *revel.Controller, user *common.User) {
 	c.Flash.Data["username"] = user.Username
 	c.Flash.Data["name"] = user.Name
 	c.Flash.Data["email"] = user.Email
+	c.Args["user"] = user
 }
 
 func unauthorized(c *revel.Controller, config *AuthenticationConfig) {
 	c.Response.Status = http.StatusUnauthorized
 	c.Result = c.RenderError(errors.New("401: Not authorized"))
-	if config.EnableBasicAuthentication {
-		c.Response.Out.Header().Add("WWW-Authenticate", `Basic realm="revel"`)
-	}
-
-	if config.EnableJwtAuthentication {
-		c.Response.Out.Header().Add("WWW-Authenticate", `Bearer`)
-	}
-}
-
-func getCredentials(data string) (username, password string, err error) {
-	decodedData, err := base64.StdEncoding.DecodeString(data)
-	if err != nil {
-		return "", "", err
+	for _, challenge := range authentication.Challenges(config.RequestOptions(), "revel") {
+		c.Response.Out.Header().Add("WWW-Authenticate", challenge)
 	}
-	strData := strings.Split(string(decodedData), ":")
-	username = strData[0]
-	password = strData[1]
-	return
 }
@@ -0,0 +1,79 @@
+package authentication
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ticketmaster/authentication/common"
+)
+
+// ValidationError lists every problem found in a configuration, each prefixed with its path in the configuration,
+// e.g. "authorization.rules[3].route[0]: ..."
+type ValidationError struct {
+	Errors []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid configuration:\n%v", strings.Join(e.Errors, "\n"))
+}
+
+// Validate loads every component of the configuration and returns all problems at once as a ValidationError.
+// Warnings point out configuration that loads but can never apply, such as authorization rules for roles no
+// authentication client grants. The components are closed again before Validate returns.
+func (c *Config) Validate() ([]string, error) {
+	manager, problems := buildManager(c)
+	manager.Close()
+	if len(problems.errors) > 0 {
+		return problems.warnings, &ValidationError{Errors: problems.errors}
+	}
+
+	return problems.warnings, nil
+}
+
+// configProblems collects the problems found loading a Config
+type configProblems struct {
+	errors   []string
+	warnings []string
+	// fatal is the first problem that prevents a Manager from being created even when loading leniently
+	fatal error
+}
+
+func (p *configProblems) add(path string, problem interface{}) {
+	p.errors = append(p.errors, fmt.Sprintf("%s: %v", path, problem))
+}
+
+func (p *configProblems) fail(err error, path string, problem interface{}) {
+	p.add(path, problem)
+	if p.fatal == nil {
+		p.fatal = err
+	}
+}
+
+// keyPath locates a key ring error by reading the algorithm and each key file on its own
+func keyPath(config *Config, algorithm string) string {
+	method, err := common.GetSigningMethod(algorithm)
+	if err != nil {
+		return "jwtAlgorithm"
+	}
+
+	if len(config.PublicKey) > 0 {
+		if _, err := common.ReadPublicKey(method, config.PublicKey); err != nil {
+			return "publicKey"
+		}
+	}
+
+	for idx, vk := range config.VerificationKeys {
+		vkMethod := method
+		if len(vk.Algorithm) > 0 {
+			vkMethod, err = common.GetSigningMethod(vk.Algorithm)
+			if err != nil {
+				return fmt.Sprintf("verificationKeys[%v].algorithm", idx)
+			}
+		}
+		if _, err := common.ReadPublicKey(vkMethod, vk.PublicKey); err != nil {
+			return fmt.Sprintf("verificationKeys[%v].publicKey", idx)
+		}
+	}
+
+	return "privateKey"
+}
@@ -0,0 +1,131 @@
+package authentication
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ticketmaster/authentication/client"
+)
+
+// closeTestDriver is a database/sql driver that never connects, for checking that connection pools are closed
+type closeTestDriver struct{}
+
+func (d closeTestDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("not supported")
+}
+
+func init() {
+	sql.Register("closetest", closeTestDriver{})
+}
+
+var invalidConfig = `
+authenticationClient:
+  - provider: memory
+    origin: testOrigin
+    allowPlaintextPasswords: true
+    users:
+      - username: test
+        password: testpass
+        roles:
+          - testRole
+  - provider: unknownProvider
+  - origin: noProvider
+authorization:
+  default: deny
+  strategy: firstMatch
+  rules:
+    - ruleType: route
+      path: /api/*
+      authorize: allow
+      role: [testRole, adminRole]
+      origin: [testOrigin, otherOrigin]
+    - ruleType: route
+      route: "^/api/[a-z"
+      authorize: allow
+      role: testRole
+      origin: testOrigin
+    - ruleType: unknown
+    - ruleType: action
+      authorize: maybe
+privateKey: test-certificates/jwt.rsa
+publicKey: test-certificates/missing.pub
+issuerURL: api.example.com
+`
+
+func TestValidate(t *testing.T) {
+	config, err := ParseConfig([]byte(invalidConfig), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	warnings, err := config.Validate()
+	if assert.IsType(t, &ValidationError{}, err) {
+		assert.Equal(t, []string{
+			"authenticationClient[1].provider: unknown provider: unknownProvider",
+			"authenticationClient[2].provider: must be specified",
+			"authorization.strategy: unknown authorization strategy: firstMatch",
+			"authorization.rules[1].route[0]: error parsing regexp: missing closing ]: `[a-z`",
+			"authorization.rules[2].ruleType: unknown rule type: unknown",
+			"authorization.rules[3].authorize: must be allow or deny",
+			"authorization.rules[3].role: must be specified",
+			"authorization.rules[3].origin: must be specified",
+			"authorization.rules[3].action: must be specified",
+			"publicKey: open test-certificates/missing.pub: no such file or directory",
+			"jwtExpiration: must be specified",
+			"issuerURL: must be an absolute http or https URL",
+		}, err.(*ValidationError).Errors)
+	}
+	assert.Nil(t, warnings)
+
+	// Rules are only checked for dead roles and origins once the configuration is valid
+	config.AuthenticationClients = config.AuthenticationClients[:1]
+	config.Authorization["strategy"] = "deny-overrides"
+	config.Authorization["rules"] = config.Authorization["rules"].([]interface{})[:1]
+	config.PublicKey = "test-certificates/jwt.rsa.pub"
+	config.JwtExpiration = 3600000000000
+	config.IssuerURL = "https://api.example.com"
+	warnings, err = config.Validate()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"authorization.rules[0].origin[1]: otherOrigin matches no configured origin",
+		"authorization.rules[0].role[1]: no authentication client grants role adminRole",
+	}, warnings)
+
+	// Roles granted by other issuers are not known
+	config.OIDCProviders = []map[interface{}]interface{}{{"name": "sso", "issuer": "https://sso", "clientID": "id", "redirectURL": "https://app/callback", "origin": "otherOrigin"}}
+	warnings, err = config.Validate()
+	assert.Nil(t, err)
+	assert.Nil(t, warnings)
+}
+
+func TestStrictConfig(t *testing.T) {
+	config, err := ParseConfig([]byte(validManagerConfig), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Lenient loading skips the unknown client
+	m, err := newManagerFromConfig(config, false)
+	if assert.Nil(t, err) {
+		assert.Equal(t, 2, len(m.AuthenticationClients))
+	}
+
+	config.Strict = true
+	_, err = newManagerFromConfig(config, false)
+	assert.EqualError(t, err, "invalid configuration:\nauthenticationClient[2].provider: unknown provider: unknownProvider")
+}
+
+func TestManagerClose(t *testing.T) {
+	config := &Config{AuthenticationClients: []map[interface{}]interface{}{
+		{"provider": "sql", "driver": "closetest", "dsn": "test", "passwordQuery": "SELECT 1"},
+	}}
+
+	// Close closes the connection pools of the clients
+	manager, _ := buildManager(config)
+	assert.Nil(t, manager.Close())
+	err := manager.AuthenticationClients[0].(*client.SQLClient).DB.Ping()
+	assert.EqualError(t, err, "sql: database is closed")
+}